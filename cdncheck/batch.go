@@ -0,0 +1,54 @@
+package cdncheck
+
+import (
+	"context"
+	"net"
+)
+
+// CheckBatch checks every IP of ips, the results are in the order of ips.
+// All the IPs are checked against the same snapshot of the ranges.
+func (c *Client) CheckBatch(ips []net.IP) ([]*Result, error) {
+	data := c.data.Load()
+	results := make([]*Result, len(ips))
+	for i, ip := range ips {
+		result, err := data.lookup(ip)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// CheckStream checks the IPs received from ips until it is closed or ctx is done.
+// The returned channel is closed once every result has been sent.
+// IPs that cannot be checked, like invalid ones, are reported as not matched.
+func (c *Client) CheckStream(ctx context.Context, ips <-chan net.IP) <-chan *Result {
+	results := make(chan *Result)
+	go func() {
+		defer close(results)
+		for {
+			var ip net.IP
+			select {
+			case <-ctx.Done():
+				return
+			case next, ok := <-ips:
+				if !ok {
+					return
+				}
+				ip = next
+			}
+
+			result, err := c.data.Load().lookup(ip)
+			if err != nil {
+				result = &Result{IP: ip}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case results <- result:
+			}
+		}
+	}()
+	return results
+}
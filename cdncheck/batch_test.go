@@ -0,0 +1,109 @@
+package cdncheck
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yl2chen/cidranger"
+)
+
+func TestCheckBatch(t *testing.T) {
+	client, err := NewFromReader(strings.NewReader(`{"cloudflare":["104.16.0.0/12"],"fastly":["104.16.0.0/12","23.235.32.0/20"],"azure":["2603:1000::/24"]}`))
+	require.Nil(t, err)
+
+	ips := []net.IP{net.ParseIP("104.16.0.1"), net.ParseIP("8.8.8.8"), net.ParseIP("23.235.32.1"), net.ParseIP("2603:1000::1")}
+	results, err := client.CheckBatch(ips)
+	require.Nil(t, err)
+	require.Len(t, results, len(ips))
+	require.Equal(t, "cloudflare", results[0].Provider, "providers announcing the same network are ordered by name")
+	require.False(t, results[1].Matched)
+	require.Equal(t, "fastly", results[2].Provider)
+	require.Equal(t, "azure", results[3].Provider)
+
+	in := make(chan net.IP)
+	go func() {
+		defer close(in)
+		for _, ip := range ips {
+			in <- ip
+		}
+	}()
+	var streamed []*Result
+	for result := range client.CheckStream(context.Background(), in) {
+		streamed = append(streamed, result)
+	}
+	require.Equal(t, results, streamed)
+}
+
+// newBenchmarkClient creates a client with providers each holding count random IPv4 ranges
+func newBenchmarkClient(providers, count int) *Client {
+	random := rand.New(rand.NewSource(1))
+	ranges := make(map[string][]string, providers)
+	for p := 0; p < providers; p++ {
+		name := fmt.Sprintf("provider%d", p)
+		for i := 0; i < count; i++ {
+			ip := make(net.IP, 4)
+			binary.BigEndian.PutUint32(ip, random.Uint32())
+			ranges[name] = append(ranges[name], fmt.Sprintf("%s/%d", ip.Mask(net.CIDRMask(20, 32)), 20))
+		}
+	}
+	client := &Client{Options: &Options{}}
+	client.data.Store(newRangeData(ranges, client.Options.categories(), &Report{}))
+	return client
+}
+
+func benchmarkIPs(count int) []net.IP {
+	random := rand.New(rand.NewSource(2))
+	ips := make([]net.IP, count)
+	for i := range ips {
+		ips[i] = make(net.IP, 4)
+		binary.BigEndian.PutUint32(ips[i], random.Uint32())
+	}
+	return ips
+}
+
+// BenchmarkCheckPerProvider measures the previous lookup iterating over one ranger per provider
+func BenchmarkCheckPerProvider(b *testing.B) {
+	client := newBenchmarkClient(10, 2000)
+	rangers := make(map[string]cidranger.Ranger)
+	for provider, cidrs := range client.Ranges() {
+		ranger := cidranger.NewPCTrieRanger()
+		for _, cidr := range cidrs {
+			_, network, _ := net.ParseCIDR(cidr)
+			_ = ranger.Insert(cidranger.NewBasicRangerEntry(*network))
+		}
+		rangers[provider] = ranger
+	}
+	ips := benchmarkIPs(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, ranger := range rangers {
+			if contains, _ := ranger.Contains(ips[i%len(ips)]); contains {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkCheck(b *testing.B) {
+	client := newBenchmarkClient(10, 2000)
+	ips := benchmarkIPs(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = client.Check(ips[i%len(ips)])
+	}
+}
+
+func BenchmarkCheckBatch(b *testing.B) {
+	client := newBenchmarkClient(10, 2000)
+	ips := benchmarkIPs(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i += len(ips) {
+		_, _ = client.CheckBatch(ips)
+	}
+}
@@ -0,0 +1,88 @@
+package cdncheck
+
+import (
+	"net"
+
+	"github.com/yl2chen/cidranger"
+)
+
+// Category is the kind of service operating the ranges of a provider
+type Category string
+
+const (
+	// CategoryUnknown is used for providers without a known category
+	CategoryUnknown Category = ""
+	// CategoryCDN is a content delivery network
+	CategoryCDN Category = "cdn"
+	// CategoryWAF is a web application firewall, usually also proxying the traffic
+	CategoryWAF Category = "waf"
+	// CategoryCloud is a cloud or hosting provider
+	CategoryCloud Category = "cloud"
+)
+
+var defaultCategories = map[string]Category{
+	"akamai":     CategoryCDN,
+	"azure":      CategoryCloud,
+	"cloudflare": CategoryWAF,
+	"cloudfront": CategoryCDN,
+	"fastly":     CategoryCDN,
+	"incapsula":  CategoryWAF,
+	"leaseweb":   CategoryCloud,
+	"sucuri":     CategoryWAF,
+}
+
+// CategoryProvider is implemented by providers declaring the category of their ranges
+type CategoryProvider interface {
+	Provider
+	Category() Category
+}
+
+type categoryProvider struct {
+	Provider
+	category Category
+}
+
+// WithCategory returns provider tagged with category
+func WithCategory(provider Provider, category Category) Provider {
+	return &categoryProvider{Provider: provider, category: category}
+}
+
+func (p *categoryProvider) Category() Category {
+	return p.category
+}
+
+// Result holds the details of a check
+type Result struct {
+	IP       net.IP
+	Matched  bool
+	Provider string
+	Category Category
+	// CIDR is the most specific range of the provider containing the IP
+	CIDR string
+}
+
+// CheckWithDetails checks if an IP belongs to a provider and returns the matched
+// provider, its category and the most specific matching CIDR.
+func (c *Client) CheckWithDetails(ip net.IP) (*Result, error) {
+	return c.data.Load().lookup(ip)
+}
+
+// mostSpecific returns the entry with the longest prefix
+func mostSpecific(entries []cidranger.RangerEntry) cidranger.RangerEntry {
+	var (
+		best     cidranger.RangerEntry
+		bestSize = -1
+	)
+	for _, entry := range entries {
+		network := entry.Network()
+		if size, _ := network.Mask.Size(); size > bestSize {
+			best, bestSize = entry, size
+		}
+	}
+	return best
+}
+
+// Category returns the category of provider name
+func (c *Client) Category(name string) Category {
+	return c.data.Load().categories[name]
+}
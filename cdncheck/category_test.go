@@ -0,0 +1,36 @@
+package cdncheck
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckWithDetails(t *testing.T) {
+	client, err := NewFromReader(strings.NewReader(`{"cloudflare":["104.16.0.0/13","104.16.0.0/12"],"azure":["13.64.0.0/11"]}`))
+	require.Nil(t, err)
+
+	result, err := client.CheckWithDetails(net.ParseIP("104.16.1.1"))
+	require.Nil(t, err)
+	require.True(t, result.Matched)
+	require.Equal(t, "cloudflare", result.Provider)
+	require.Equal(t, CategoryWAF, result.Category)
+	require.Equal(t, "104.16.0.0/13", result.CIDR)
+
+	result, err = client.CheckWithDetails(net.ParseIP("13.64.0.1"))
+	require.Nil(t, err)
+	require.Equal(t, CategoryCloud, result.Category)
+
+	result, err = client.CheckWithDetails(net.ParseIP("8.8.8.8"))
+	require.Nil(t, err)
+	require.False(t, result.Matched)
+
+	client.AddRanges("edge", "10.0.0.0/8")
+	require.Equal(t, CategoryUnknown, client.Category("edge"))
+	require.Nil(t, client.AddProvider(WithCategory(NewStaticProvider("imperva", "45.60.0.0/16"), CategoryWAF)))
+	result, err = client.CheckWithDetails(net.ParseIP("45.60.0.1"))
+	require.Nil(t, err)
+	require.Equal(t, CategoryWAF, result.Category)
+}
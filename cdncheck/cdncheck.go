@@ -1,9 +1,13 @@
 package cdncheck
 
 import (
-	"crypto/tls"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/yl2chen/cidranger"
@@ -12,11 +16,32 @@ import (
 // Client checks for CDN based IPs which should be excluded
 // during scans since they belong to third party firewalls.
 type Client struct {
-	Options *Options
-	ranges  map[string][]string
-	rangers map[string]cidranger.Ranger
+	Options    *Options
+	httpClient *http.Client
+	// data holds the loaded ranges, it is swapped atomically on refresh
+	data atomic.Pointer[rangeData]
+	// mu serializes the updates of data and Options
+	mu sync.Mutex
+	// base holds the ranges read by NewFromReader, refreshes load them again
+	// instead of fetching the built-in providers
+	base map[string][]string
+
+	refreshMu      sync.RWMutex
+	lastRefresh    time.Time
+	lastRefreshErr error
+}
+
+// rangeData is an immutable snapshot of the loaded ranges
+type rangeData struct {
+	ranges map[string][]string
+	// merged indexes the ranges of every provider in a single trie
+	merged     cidranger.Ranger
+	categories map[string]Category
+	report     *Report
 }
 
+const defaultProviderTimeout = 30 * time.Second
+
 var defaultScrapers = map[string]scraperFunc{
 	"azure":      scrapeAzure,
 	"cloudflare": scrapeCloudflare,
@@ -41,112 +66,317 @@ func NewWithCache() (*Client, error) {
 	return new(&Options{Cache: true})
 }
 
+// NewFromFile creates a new firewall IP checking client from a local ranges file,
+// without any network access. The file uses the format written by Client.Export.
+func NewFromFile(path string) (*Client, error) {
+	return new(&Options{RangesFile: path})
+}
+
+// NewFromReader creates a new firewall IP checking client from JSON or YAML ranges data,
+// without any network access. Refreshes keep the ranges read.
+func NewFromReader(r io.Reader) (*Client, error) {
+	ranges, err := readRanges(r)
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{Options: &Options{}, httpClient: http.DefaultClient, base: ranges}
+	data, err := client.getCDNData(nil)
+	if err != nil {
+		return nil, err
+	}
+	client.data.Store(data)
+	client.lastRefresh = time.Now()
+	return client, nil
+}
+
 // NewWithOptions creates a new instance with options
 func NewWithOptions(Options *Options) (*Client, error) {
 	return new(Options)
 }
 
 func new(options *Options) (*Client, error) {
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			MaxIdleConns:        100,
-			MaxIdleConnsPerHost: 100,
-			TLSClientConfig: &tls.Config{
-				Renegotiation:      tls.RenegotiateOnceAsClient,
-				InsecureSkipVerify: true,
-			},
-		},
-		Timeout: time.Duration(30) * time.Second,
-	}
-	client := &Client{Options: options}
-
-	var err error
-	if options.Cache {
-		err = client.getCDNDataFromCache(httpClient)
-	} else {
-		err = client.getCDNData(httpClient)
+	httpClient, err := newHTTPClient(options)
+	if err != nil {
+		return nil, err
 	}
+	client := &Client{Options: options, httpClient: httpClient}
+	data, err := client.getCDNData(nil)
 	if err != nil {
 		return nil, err
 	}
+	client.data.Store(data)
+	client.lastRefresh = time.Now()
 	return client, nil
 }
 
-func (c *Client) getCDNData(httpClient *http.Client) error {
-	c.ranges = make(map[string][]string)
-	c.rangers = make(map[string]cidranger.Ranger)
+// getCDNData loads the base ranges (ranges read by NewFromReader, local file,
+// projectdiscovery cache or the built-in providers), fetches the custom providers and keeps the enabled ones.
+// The ranges of providers failing to load are taken from previous when available.
+func (c *Client) getCDNData(previous *rangeData) (*rangeData, error) {
+	var (
+		ranges    map[string][]string
+		providers []Provider
+		source    Source
+		err       error
+	)
+	switch {
+	case c.base != nil:
+		ranges = make(map[string][]string, len(c.base))
+		for name, cidrs := range c.base {
+			ranges[name] = cidrs
+		}
+		providers = c.Options.Providers
+		source = SourceFile
+	case c.Options.RangesFile != "":
+		ranges, err = readRangesFile(c.Options.RangesFile)
+		providers = c.Options.Providers
+		source = SourceFile
+	case c.Options.Cache:
+		ranges, err = scrapeProjectDiscovery(c.httpClient)
+		providers = c.Options.Providers
+		source = SourceNetwork
+	default:
+		ranges = make(map[string][]string)
+		providers = c.Options.providers()
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	for provider, scraper := range defaultScrapers {
-		cidrs, err := scraper(httpClient)
-		if err != nil {
-			return err
+	for name := range ranges {
+		if !c.Options.IsProviderEnabled(name) {
+			delete(ranges, name)
 		}
+	}
 
-		c.ranges[provider] = cidrs
-		ranger := cidranger.NewPCTrieRanger()
-		for _, cidr := range cidrs {
-			_, network, err := net.ParseCIDR(cidr)
-			if err != nil {
-				continue
-			}
-			_ = ranger.Insert(cidranger.NewBasicRangerEntry(*network))
+	report := &Report{}
+	fetched := make(map[string]struct{})
+	for _, result := range c.fetchProviders(providers) {
+		fetched[result.Name] = struct{}{}
+		report.Providers = append(report.Providers, result.ProviderReport)
+		if result.Err == nil {
+			ranges[result.Name] = result.cidrs
+		} else if cidrs, ok := previous.providerRanges(result.Name); ok {
+			ranges[result.Name] = cidrs
 		}
 	}
-	if c.Options.HasAuthInfo() {
-		for provider, scraper := range defaultScrapersWithOptions {
-			cidrs, err := scraper(httpClient, c.Options)
-			if err != nil {
-				return err
-			}
+	for name, cidrs := range ranges {
+		if _, ok := fetched[name]; !ok {
+			report.Providers = append(report.Providers, ProviderReport{Name: name, CIDRs: len(cidrs), Source: source})
+		}
+	}
+	report.sort()
 
-			c.ranges[provider] = cidrs
-			ranger := cidranger.NewPCTrieRanger()
-			for _, cidr := range cidrs {
-				_, network, err := net.ParseCIDR(cidr)
-				if err != nil {
-					continue
-				}
-				_ = ranger.Insert(cidranger.NewBasicRangerEntry(*network))
-			}
+	if err := report.Err(); err != nil && (c.Options.FailOnProviderError || len(ranges) == 0) {
+		return nil, err
+	}
+	return newRangeData(ranges, c.Options.categories(), report), nil
+}
+
+type providerResult struct {
+	ProviderReport
+	cidrs []string
+}
+
+// fetchProviders fetches the enabled providers in parallel, each one bounded by the provider timeout
+func (c *Client) fetchProviders(providers []Provider) []providerResult {
+	timeout := c.Options.ProviderTimeout
+	if timeout <= 0 {
+		timeout = defaultProviderTimeout
+	}
+	httpClient := *c.httpClient
+	if httpClient.Timeout == 0 || httpClient.Timeout > timeout {
+		httpClient.Timeout = timeout
+	}
+
+	var enabled []Provider
+	for _, provider := range providers {
+		if c.Options.IsProviderEnabled(provider.Name()) {
+			enabled = append(enabled, provider)
 		}
 	}
-	return nil
+
+	results := make([]providerResult, len(enabled))
+	var wg sync.WaitGroup
+	for i, provider := range enabled {
+		wg.Add(1)
+		go func(result *providerResult, provider Provider) {
+			defer wg.Done()
+			*result = c.fetchCachedProvider(provider, &httpClient, timeout)
+		}(&results[i], provider)
+	}
+	wg.Wait()
+	return results
+}
+
+// fetchProvider fetches provider, giving up once timeout elapses
+func fetchProvider(provider Provider, httpClient *http.Client, options *Options, timeout time.Duration) providerResult {
+	start := time.Now()
+	done := make(chan providerResult, 1)
+	go func() {
+		cidrs, err := provider.Fetch(httpClient, options)
+		done <- providerResult{ProviderReport{Err: err}, cidrs}
+	}()
+
+	var result providerResult
+	select {
+	case result = <-done:
+	case <-time.After(timeout):
+		result.Err = fmt.Errorf("timed out after %s", timeout)
+	}
+	result.Name = provider.Name()
+	result.CIDRs = len(result.cidrs)
+	result.Duration = time.Since(start)
+	if result.Err == nil {
+		result.Source = SourceNetwork
+		result.FetchedAt = time.Now()
+	}
+	return result
 }
 
-func (c *Client) getCDNDataFromCache(httpClient *http.Client) error {
-	var err error
-	c.ranges, err = scrapeProjectDiscovery(httpClient)
+// Report returns the outcome of loading each provider
+func (c *Client) Report() *Report {
+	return c.data.Load().report
+}
+
+// AddProvider fetches provider and adds its ranges to the client,
+// replacing the ranges of any provider with the same name.
+// The provider is registered on the options so that refreshes fetch it again.
+func (c *Client) AddProvider(provider Provider) error {
+	cidrs, err := provider.Fetch(c.httpClient, c.Options)
 	if err != nil {
 		return err
 	}
 
-	c.rangers = make(map[string]cidranger.Ranger)
-	for provider, ranges := range c.ranges {
-		ranger := cidranger.NewPCTrieRanger()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Options.RegisterProvider(provider)
+	c.Options.enable(provider.Name())
+	c.update(func(ranges map[string][]string) {
+		ranges[provider.Name()] = cidrs
+	})
+	return nil
+}
+
+// AddRanges sets the ranges of provider name to cidrs
+func (c *Client) AddRanges(name string, cidrs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Options.AddStaticRanges(name, cidrs...)
+	c.Options.enable(name)
+	c.update(func(ranges map[string][]string) {
+		ranges[name] = cidrs
+	})
+}
+
+// RemoveProvider drops the ranges of provider name from the client
+func (c *Client) RemoveProvider(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Options.DisableProviders(name)
+	c.update(func(ranges map[string][]string) {
+		delete(ranges, name)
+	})
+}
+
+// update applies fn to a copy of the loaded ranges and swaps the result in.
+// The caller must hold c.mu.
+func (c *Client) update(fn func(ranges map[string][]string)) {
+	data := c.data.Load()
+	ranges := make(map[string][]string, len(data.ranges)+1)
+	for provider, cidrs := range data.ranges {
+		ranges[provider] = cidrs
+	}
+	fn(ranges)
+	c.data.Store(newRangeData(ranges, c.Options.categories(), data.report))
+}
+
+// newRangeData indexes ranges in a single ranger keyed to the providers
+func newRangeData(ranges map[string][]string, categories map[string]Category, report *Report) *rangeData {
+	data := &rangeData{
+		ranges:     ranges,
+		merged:     cidranger.NewPCTrieRanger(),
+		categories: categories,
+		report:     report,
+	}
 
-		for _, cidr := range ranges {
+	providers := make([]string, 0, len(ranges))
+	for provider := range ranges {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	entries := make(map[string]*providerEntry)
+	for _, provider := range providers {
+		for _, cidr := range ranges[provider] {
 			_, network, err := net.ParseCIDR(cidr)
 			if err != nil {
 				continue
 			}
-			_ = ranger.Insert(cidranger.NewBasicRangerEntry(*network))
+			entry, ok := entries[network.String()]
+			if !ok {
+				entry = &providerEntry{network: *network}
+				entries[network.String()] = entry
+			}
+			if last := len(entry.providers) - 1; last < 0 || entry.providers[last] != provider {
+				entry.providers = append(entry.providers, provider)
+			}
 		}
-		c.rangers[provider] = ranger
 	}
-	return nil
+	for _, entry := range entries {
+		_ = data.merged.Insert(entry)
+	}
+	return data
+}
+
+// providerEntry is a merged ranger entry, the same network may be announced by several providers
+type providerEntry struct {
+	network   net.IPNet
+	providers []string
+}
+
+func (entry *providerEntry) Network() net.IPNet {
+	return entry.network
+}
+
+// lookup returns the most specific network containing ip in the merged ranger
+func (data *rangeData) lookup(ip net.IP) (*Result, error) {
+	result := &Result{IP: ip}
+	entries, err := data.merged.ContainingNetworks(ip)
+	if err != nil {
+		return nil, err
+	}
+	if entry, ok := mostSpecific(entries).(*providerEntry); ok {
+		result.Matched = true
+		result.Provider = entry.providers[0]
+		result.Category = data.categories[result.Provider]
+		result.CIDR = entry.network.String()
+	}
+	return result, nil
+}
+
+// providerRanges returns the ranges loaded for provider name
+func (data *rangeData) providerRanges(name string) ([]string, bool) {
+	if data == nil {
+		return nil, false
+	}
+	cidrs, ok := data.ranges[name]
+	return cidrs, ok
 }
 
 // Check checks if an IP is contained in the blacklist
 func (c *Client) Check(ip net.IP) (bool, string, error) {
-	for provider, ranger := range c.rangers {
-		if contains, err := ranger.Contains(ip); contains {
-			return true, provider, err
-		}
+	result, err := c.data.Load().lookup(ip)
+	if err != nil {
+		return false, "", err
 	}
-	return false, "", nil
+	return result.Matched, result.Provider, nil
 }
 
 // Ranges returns the providers and ranges for the cdn client
 func (c *Client) Ranges() map[string][]string {
-	return c.ranges
+	return c.data.Load().ranges
 }
@@ -0,0 +1,114 @@
+package cdncheck
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Source tells where the ranges of a provider were loaded from
+type Source string
+
+const (
+	SourceNetwork    Source = "network"
+	SourceCache      Source = "cache"
+	SourceStaleCache Source = "stale-cache"
+	SourceFile       Source = "file"
+)
+
+// DefaultCacheTTL is the time cached ranges are reused when Options.CacheTTL is not set
+const DefaultCacheTTL = 24 * time.Hour
+
+// cacheEntry is the on-disk representation of the ranges of a provider
+type cacheEntry struct {
+	Provider  string    `json:"provider"`
+	FetchedAt time.Time `json:"fetched_at"`
+	CIDRs     []string  `json:"cidrs"`
+}
+
+// cachePath returns the cache file of provider name
+func cachePath(dir, name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(name)
+	return filepath.Join(dir, name+".json")
+}
+
+func readCacheEntry(dir, name string) (*cacheEntry, error) {
+	data, err := os.ReadFile(cachePath(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// writeCacheEntry stores entry through a temporary file so readers never see a partial write
+func writeCacheEntry(dir string, entry *cacheEntry) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), cachePath(dir, entry.Provider))
+}
+
+// fetchCachedProvider returns the cached ranges of provider while they are fresh,
+// otherwise it fetches them and updates the cache. When fetching fails the stale
+// cached ranges are used instead.
+func (c *Client) fetchCachedProvider(provider Provider, httpClient *http.Client, timeout time.Duration) providerResult {
+	dir := c.Options.CacheDir
+	if _, static := provider.(*staticProvider); dir == "" || static {
+		return fetchProvider(provider, httpClient, c.Options, timeout)
+	}
+	ttl := c.Options.CacheTTL
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+
+	entry, cacheErr := readCacheEntry(dir, provider.Name())
+	if cacheErr == nil && time.Since(entry.FetchedAt) < ttl {
+		return cachedResult(entry, SourceCache)
+	}
+
+	result := fetchProvider(provider, httpClient, c.Options, timeout)
+	if result.Err == nil {
+		_ = writeCacheEntry(dir, &cacheEntry{Provider: provider.Name(), FetchedAt: time.Now(), CIDRs: result.cidrs})
+		return result
+	}
+	if cacheErr == nil {
+		return cachedResult(entry, SourceStaleCache)
+	}
+	return result
+}
+
+func cachedResult(entry *cacheEntry, source Source) providerResult {
+	return providerResult{
+		ProviderReport: ProviderReport{
+			Name:      entry.Provider,
+			CIDRs:     len(entry.CIDRs),
+			Source:    source,
+			FetchedAt: entry.FetchedAt,
+		},
+		cidrs: entry.CIDRs,
+	}
+}
@@ -0,0 +1,74 @@
+package cdncheck
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskCache(t *testing.T) {
+	var calls int32
+	var fail atomic.Bool
+	options := &Options{EnabledProviders: []string{"vendor"}, CacheDir: t.TempDir() + "/cache", CacheTTL: time.Hour}
+	options.RegisterProvider(NewProvider("vendor", func(*http.Client, *Options) ([]string, error) {
+		atomic.AddInt32(&calls, 1)
+		if fail.Load() {
+			return nil, errors.New("banned")
+		}
+		return []string{"10.0.0.0/8"}, nil
+	}))
+
+	client, err := NewWithOptions(options)
+	require.Nil(t, err)
+	require.Equal(t, SourceNetwork, client.Report().Providers[0].Source)
+
+	// a second process reuses the fresh cache
+	client, err = NewWithOptions(options)
+	require.Nil(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	require.Equal(t, SourceCache, client.Report().Providers[0].Source)
+	require.Equal(t, []string{"10.0.0.0/8"}, client.Ranges()["vendor"])
+
+	// expired entries are fetched again, and used as fallback when fetching fails
+	options.CacheTTL = time.Nanosecond
+	fail.Store(true)
+	client, err = NewWithOptions(options)
+	require.Nil(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	report := client.Report().Providers[0]
+	require.Equal(t, SourceStaleCache, report.Source)
+	require.Nil(t, report.Err)
+	require.False(t, report.FetchedAt.IsZero())
+	require.Equal(t, []string{"10.0.0.0/8"}, client.Ranges()["vendor"])
+}
+
+func TestDiskCacheBannedProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Access denied", http.StatusForbidden)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	dir := t.TempDir()
+	require.Nil(t, writeCacheEntry(dir, &cacheEntry{Provider: "fastly", FetchedAt: time.Now().Add(-48 * time.Hour), CIDRs: []string{"1.0.0.0/8"}}))
+	options := &Options{
+		EnabledProviders: []string{"fastly"},
+		CacheDir:         dir,
+		HTTPClient:       &http.Client{Transport: &redirectTransport{target: target}},
+	}
+	client, err := NewWithOptions(options)
+	require.Nil(t, err)
+	report := client.Report().Providers[0]
+	require.Equal(t, SourceStaleCache, report.Source)
+	require.Equal(t, []string{"1.0.0.0/8"}, client.Ranges()["fastly"])
+
+	entry, err := readCacheEntry(dir, "fastly")
+	require.Nil(t, err)
+	require.Equal(t, []string{"1.0.0.0/8"}, entry.CIDRs)
+}
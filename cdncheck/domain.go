@@ -0,0 +1,183 @@
+package cdncheck
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultResolvers are the DNS servers used when none are configured
+var DefaultResolvers = []string{"1.1.1.1:53", "8.8.8.8:53"}
+
+// defaultCNAMESuffixes maps the CNAME suffixes of CDN edges to their provider
+var defaultCNAMESuffixes = map[string]string{
+	"akamai.net":         "akamai",
+	"akamaiedge.net":     "akamai",
+	"akamaized.net":      "akamai",
+	"edgekey.net":        "akamai",
+	"edgesuite.net":      "akamai",
+	"azureedge.net":      "azure",
+	"azurefd.net":        "azure",
+	"trafficmanager.net": "azure",
+	"cdn.cloudflare.net": "cloudflare",
+	"cloudfront.net":     "cloudfront",
+	"fastly.net":         "fastly",
+	"fastlylb.net":       "fastly",
+	"incapdns.net":       "incapsula",
+	"impervadns.net":     "incapsula",
+	"sucuri.net":         "sucuri",
+	"sucuridns.com":      "sucuri",
+	"lswcdn.net":         "leaseweb",
+}
+
+// Resolver performs DNS queries for CheckDomain
+type Resolver interface {
+	Resolve(name string, qtype uint16) (*dns.Msg, error)
+}
+
+type dnsResolver struct {
+	client  *dns.Client
+	servers []string
+}
+
+// NewResolver creates a resolver querying servers (host:port) in order until one answers
+func NewResolver(servers ...string) Resolver {
+	if len(servers) == 0 {
+		servers = DefaultResolvers
+	}
+	return &dnsResolver{
+		client:  &dns.Client{Timeout: 5 * time.Second},
+		servers: servers,
+	}
+}
+
+func (r *dnsResolver) Resolve(name string, qtype uint16) (*dns.Msg, error) {
+	msg := &dns.Msg{}
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+
+	var lastErr error
+	for _, server := range r.servers {
+		answer, _, err := r.client.Exchange(msg, server)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if answer.Rcode != dns.RcodeSuccess && answer.Rcode != dns.RcodeNameError {
+			lastErr = fmt.Errorf("%s: %s", server, dns.RcodeToString[answer.Rcode])
+			continue
+		}
+		return answer, nil
+	}
+	return nil, lastErr
+}
+
+// DomainResult holds the details of a domain check
+type DomainResult struct {
+	Domain string
+	// CNAMEs is the CNAME chain of the domain
+	CNAMEs []string
+	// IPs are the A and AAAA records of the domain
+	IPs []net.IP
+	// Matched reports whether a CNAME or an IP belongs to a provider
+	Matched  bool
+	Provider string
+	Category Category
+	// CNAME is the CNAME matching a provider suffix, if any
+	CNAME string
+	// Results holds the check of each resolved IP
+	Results []*Result
+}
+
+// CheckDomain resolves the A/AAAA records of domain and checks each address,
+// it also matches the CNAME chain against the known CDN suffixes.
+func (c *Client) CheckDomain(domain string) (*DomainResult, error) {
+	result := &DomainResult{Domain: strings.TrimSuffix(domain, ".")}
+	resolver := c.resolver()
+
+	var lookupErr error
+	seen := make(map[string]struct{})
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		answer, err := resolver.Resolve(domain, qtype)
+		if err != nil {
+			lookupErr = err
+			continue
+		}
+		for _, record := range answer.Answer {
+			switch record := record.(type) {
+			case *dns.CNAME:
+				target := strings.TrimSuffix(strings.ToLower(record.Target), ".")
+				if _, ok := seen[target]; !ok {
+					seen[target] = struct{}{}
+					result.CNAMEs = append(result.CNAMEs, target)
+				}
+			case *dns.A:
+				result.IPs = append(result.IPs, record.A)
+			case *dns.AAAA:
+				result.IPs = append(result.IPs, record.AAAA)
+			}
+		}
+	}
+	if lookupErr != nil && len(result.IPs) == 0 && len(result.CNAMEs) == 0 {
+		return nil, lookupErr
+	}
+
+	for _, ip := range result.IPs {
+		ipResult, err := c.CheckWithDetails(ip)
+		if err != nil {
+			return nil, err
+		}
+		result.Results = append(result.Results, ipResult)
+		if ipResult.Matched && !result.Matched {
+			result.Matched = true
+			result.Provider = ipResult.Provider
+			result.Category = ipResult.Category
+		}
+	}
+
+	for _, cname := range result.CNAMEs {
+		if provider, ok := c.matchCNAME(cname); ok {
+			result.CNAME = cname
+			if !result.Matched {
+				result.Matched = true
+				result.Provider = provider
+				result.Category = c.Category(provider)
+			}
+			break
+		}
+	}
+	return result, nil
+}
+
+// matchCNAME returns the provider of the longest suffix matching cname,
+// the custom suffixes taking precedence over the built-in ones of the same length
+func (c *Client) matchCNAME(cname string) (string, bool) {
+	var (
+		provider string
+		best     string
+		matched  bool
+	)
+	match := func(suffixes map[string]string, override bool) {
+		for suffix, name := range suffixes {
+			suffix = strings.Trim(strings.ToLower(suffix), ".")
+			if cname != suffix && !strings.HasSuffix(cname, "."+suffix) {
+				continue
+			}
+			if !matched || len(suffix) > len(best) || override && len(suffix) == len(best) {
+				best, provider, matched = suffix, name, true
+			}
+		}
+	}
+	match(defaultCNAMESuffixes, false)
+	match(c.Options.CNAMESuffixes, true)
+	return provider, matched
+}
+
+func (c *Client) resolver() Resolver {
+	if c.Options.Resolver != nil {
+		return c.Options.Resolver
+	}
+	return NewResolver(c.Options.Resolvers...)
+}
@@ -0,0 +1,93 @@
+package cdncheck
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// zone holds the records served by the stub DNS server
+var zone = map[string][]string{
+	"static.example.com.": {
+		"static.example.com. 60 IN CNAME d111111abcdef8.cloudfront.net.",
+		"d111111abcdef8.cloudfront.net. 60 IN A 13.224.0.10",
+	},
+	"www.example.com.": {
+		"www.example.com. 60 IN A 104.16.1.1",
+		"www.example.com. 60 IN AAAA 2606:4700::1",
+	},
+	"origin.example.com.": {
+		"origin.example.com. 60 IN A 192.0.2.10",
+	},
+}
+
+func newStubResolver(t *testing.T) Resolver {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+
+	started := make(chan struct{})
+	server := &dns.Server{
+		PacketConn:        conn,
+		NotifyStartedFunc: func() { close(started) },
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			m := &dns.Msg{}
+			m.SetReply(r)
+			question := r.Question[0]
+			for _, record := range zone[question.Name] {
+				rr, err := dns.NewRR(record)
+				if err != nil {
+					continue
+				}
+				if rr.Header().Rrtype == question.Qtype || rr.Header().Rrtype == dns.TypeCNAME {
+					m.Answer = append(m.Answer, rr)
+				}
+			}
+			_ = w.WriteMsg(m)
+		}),
+	}
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	return NewResolver(conn.LocalAddr().String())
+}
+
+func TestCheckDomain(t *testing.T) {
+	client, err := NewFromReader(strings.NewReader(`{"cloudflare":["104.16.0.0/13","2606:4700::/32"]}`))
+	require.Nil(t, err)
+	client.Options.Resolver = newStubResolver(t)
+
+	result, err := client.CheckDomain("www.example.com")
+	require.Nil(t, err)
+	require.True(t, result.Matched)
+	require.Equal(t, "cloudflare", result.Provider)
+	require.Equal(t, CategoryWAF, result.Category)
+	require.Len(t, result.IPs, 2)
+	require.True(t, result.Results[1].Matched)
+
+	result, err = client.CheckDomain("static.example.com")
+	require.Nil(t, err)
+	require.True(t, result.Matched)
+	require.Equal(t, "cloudfront", result.Provider)
+	require.Equal(t, CategoryCDN, result.Category)
+	require.Equal(t, "d111111abcdef8.cloudfront.net", result.CNAME)
+	require.Equal(t, []string{"d111111abcdef8.cloudfront.net"}, result.CNAMEs)
+
+	result, err = client.CheckDomain("origin.example.com")
+	require.Nil(t, err)
+	require.False(t, result.Matched)
+
+	client.Options.CNAMESuffixes = map[string]string{"d111111abcdef8.cloudfront.net": "internal"}
+	result, err = client.CheckDomain("static.example.com")
+	require.Nil(t, err)
+	require.Equal(t, "internal", result.Provider)
+
+	// a custom suffix overrides the built-in one
+	client.Options.CNAMESuffixes = map[string]string{"cloudfront.net": "edge"}
+	result, err = client.CheckDomain("static.example.com")
+	require.Nil(t, err)
+	require.Equal(t, "edge", result.Provider)
+}
@@ -0,0 +1,66 @@
+package cdncheck
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// newHTTPClient returns the client used by the scrapers. Options.HTTPClient is used
+// as-is when set, otherwise a client is built from the proxy, TLS and timeout options.
+// The user agent and headers of options are added to every request.
+func newHTTPClient(options *Options) (*http.Client, error) {
+	var client http.Client
+	if options.HTTPClient != nil {
+		client = *options.HTTPClient
+	} else {
+		transport := &http.Transport{
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 100,
+			TLSClientConfig: &tls.Config{
+				Renegotiation:      tls.RenegotiateOnceAsClient,
+				InsecureSkipVerify: !options.VerifyTLS,
+			},
+		}
+		if options.Proxy != "" {
+			proxyURL, err := url.Parse(options.Proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy url: %w", err)
+			}
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+		client.Transport = transport
+		client.Timeout = options.Timeout
+		if client.Timeout <= 0 {
+			client.Timeout = defaultProviderTimeout
+		}
+	}
+
+	if options.UserAgent != "" || len(options.Headers) > 0 {
+		next := client.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		client.Transport = &headerTransport{next: next, userAgent: options.UserAgent, headers: options.Headers}
+	}
+	return &client, nil
+}
+
+// headerTransport sets the configured headers on every request
+type headerTransport struct {
+	next      http.RoundTripper
+	userAgent string
+	headers   map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,61 @@
+package cdncheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClientOptions(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		_, _ = w.Write([]byte("10.0.0.0/8"))
+	}))
+	defer server.Close()
+
+	fetch := func(options *Options) error {
+		httpClient, err := newHTTPClient(options)
+		require.Nil(t, err)
+		resp, err := httpClient.Get(server.URL)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	err := fetch(&Options{UserAgent: "scanner/1.0", Headers: map[string]string{"X-Team": "red"}})
+	require.Nil(t, err)
+	require.Equal(t, "scanner/1.0", headers.Get("User-Agent"))
+	require.Equal(t, "red", headers.Get("X-Team"))
+
+	err = fetch(&Options{VerifyTLS: true})
+	require.NotNil(t, err, "self-signed certificate must be rejected")
+
+	err = fetch(&Options{HTTPClient: server.Client(), UserAgent: "custom"})
+	require.Nil(t, err)
+	require.Equal(t, "custom", headers.Get("User-Agent"))
+
+	_, err = newHTTPClient(&Options{Proxy: "http://[::1"})
+	require.NotNil(t, err)
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	var proxyAuth, target string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyAuth = r.Header.Get("Proxy-Authorization")
+		target = r.URL.String()
+		_, _ = w.Write([]byte("173.245.48.0/20"))
+	}))
+	defer proxy.Close()
+
+	httpClient, err := newHTTPClient(&Options{Proxy: "http://user:secret@" + proxy.Listener.Addr().String()})
+	require.Nil(t, err)
+	resp, err := httpClient.Get("http://www.cloudflare.com/ips-v4")
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, "http://www.cloudflare.com/ips-v4", target)
+	require.Equal(t, "Basic dXNlcjpzZWNyZXQ=", proxyAuth)
+}
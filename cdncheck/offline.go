@@ -0,0 +1,104 @@
+package cdncheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the encoding of an exported ranges file
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// formatFromPath returns the format matching the extension of path, defaulting to json
+func formatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		return FormatJSON
+	}
+}
+
+// readRanges decodes provider->CIDR data, accepting both JSON and YAML
+func readRanges(r io.Reader) (map[string][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranges map[string][]string
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(trimmed, &ranges)
+	} else {
+		err = yaml.Unmarshal(data, &ranges)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decode ranges: %w", err)
+	}
+	if ranges == nil {
+		ranges = make(map[string][]string)
+	}
+	return ranges, nil
+}
+
+// readRangesFile decodes the ranges stored in the JSON/YAML file at path
+func readRangesFile(path string) (map[string][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readRanges(file)
+}
+
+// Export writes the currently loaded ranges to w in the given format.
+// The output can be loaded back with NewFromFile or NewFromReader.
+func (c *Client) Export(w io.Writer, format Format) error {
+	loaded := c.Ranges()
+	ranges := make(map[string][]string, len(loaded))
+	for provider, cidrs := range loaded {
+		sorted := append([]string(nil), cidrs...)
+		sort.Strings(sorted)
+		ranges[provider] = sorted
+	}
+
+	switch format {
+	case FormatJSON, "":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(ranges)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		defer encoder.Close()
+		return encoder.Encode(ranges)
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// ExportToFile writes the currently loaded ranges to path, picking the
+// format from the file extension (.yaml/.yml for YAML, JSON otherwise).
+func (c *Client) ExportToFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := c.Export(file, formatFromPath(path)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
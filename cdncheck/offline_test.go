@@ -0,0 +1,72 @@
+package cdncheck
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineRanges(t *testing.T) {
+	client, err := NewFromReader(strings.NewReader(`{"cloudflare":["173.245.48.0/20"],"fastly":["23.235.32.0/20"]}`))
+	require.Nil(t, err)
+
+	found, provider, err := client.Check(net.ParseIP("173.245.48.1"))
+	require.Nil(t, err)
+	require.True(t, found)
+	require.Equal(t, "cloudflare", provider)
+
+	t.Run("yaml", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.Nil(t, client.Export(&buf, FormatYAML))
+		imported, err := NewFromReader(&buf)
+		require.Nil(t, err)
+		require.Equal(t, client.Ranges(), imported.Ranges())
+	})
+
+	t.Run("file", func(t *testing.T) {
+		for _, name := range []string{"ranges.json", "ranges.yaml"} {
+			path := filepath.Join(t.TempDir(), name)
+			require.Nil(t, client.ExportToFile(path))
+			imported, err := NewFromFile(path)
+			require.Nil(t, err)
+			require.Equal(t, client.Ranges(), imported.Ranges())
+			found, provider, _ := imported.Check(net.ParseIP("23.235.32.10"))
+			require.True(t, found)
+			require.Equal(t, "fastly", provider)
+		}
+	})
+}
+
+// failingTransport fails the test on any request
+type failingTransport struct {
+	t *testing.T
+}
+
+func (f failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.t.Errorf("unexpected request to %s", req.URL)
+	return nil, errors.New("network disabled")
+}
+
+func TestOfflineRefresh(t *testing.T) {
+	client, err := NewFromReader(strings.NewReader(`{"cloudflare":["173.245.48.0/20"],"internal":["10.0.0.0/8"]}`))
+	require.Nil(t, err)
+	client.httpClient = &http.Client{Transport: failingTransport{t}}
+
+	require.Nil(t, client.Refresh())
+	require.Equal(t, map[string][]string{
+		"cloudflare": {"173.245.48.0/20"},
+		"internal":   {"10.0.0.0/8"},
+	}, client.Ranges())
+	found, provider, _ := client.Check(net.ParseIP("10.1.2.3"))
+	require.True(t, found)
+	require.Equal(t, "internal", provider)
+	for _, provider := range client.Report().Providers {
+		require.Equal(t, SourceFile, provider.Source)
+	}
+}
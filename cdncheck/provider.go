@@ -0,0 +1,84 @@
+package cdncheck
+
+import (
+	"net/http"
+	"sort"
+)
+
+// Provider is a source of CIDR ranges for a CDN, WAF or cloud vendor
+type Provider interface {
+	// Name returns the name the ranges are registered under
+	Name() string
+	// Fetch returns the CIDR ranges currently announced by the provider
+	Fetch(httpClient *http.Client, options *Options) ([]string, error)
+}
+
+// FetchFunc fetches the CIDR ranges of a provider
+type FetchFunc func(httpClient *http.Client, options *Options) ([]string, error)
+
+type funcProvider struct {
+	name  string
+	fetch FetchFunc
+}
+
+// NewProvider creates a provider named name whose ranges are returned by fetch
+func NewProvider(name string, fetch FetchFunc) Provider {
+	return &funcProvider{name: name, fetch: fetch}
+}
+
+func (p *funcProvider) Name() string {
+	return p.name
+}
+
+func (p *funcProvider) Fetch(httpClient *http.Client, options *Options) ([]string, error) {
+	return p.fetch(httpClient, options)
+}
+
+type staticProvider struct {
+	name  string
+	cidrs []string
+}
+
+// NewStaticProvider creates a provider serving a fixed list of CIDR ranges
+func NewStaticProvider(name string, cidrs ...string) Provider {
+	return &staticProvider{name: name, cidrs: cidrs}
+}
+
+func (p *staticProvider) Name() string {
+	return p.name
+}
+
+func (p *staticProvider) Fetch(httpClient *http.Client, options *Options) ([]string, error) {
+	return append([]string(nil), p.cidrs...), nil
+}
+
+// DefaultProviders returns the names of the built-in providers
+func DefaultProviders() []string {
+	var names []string
+	for name := range defaultScrapers {
+		names = append(names, name)
+	}
+	for name := range defaultScrapersWithOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// defaultProviders returns the built-in providers usable with options.
+// The ipinfo based providers are only returned when a token is configured.
+func defaultProviders(options *Options) []Provider {
+	var providers []Provider
+	for name, scraper := range defaultScrapers {
+		scraper := scraper
+		providers = append(providers, NewProvider(name, func(httpClient *http.Client, _ *Options) ([]string, error) {
+			return scraper(httpClient)
+		}))
+	}
+	if options.HasAuthInfo() {
+		for name, scraper := range defaultScrapersWithOptions {
+			providers = append(providers, NewProvider(name, FetchFunc(scraper)))
+		}
+	}
+	return providers
+}
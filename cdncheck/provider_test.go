@@ -0,0 +1,94 @@
+package cdncheck
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ranges.json")
+	require.Nil(t, os.WriteFile(path, []byte(`{"cloudflare":["173.245.48.0/20"],"azure":["13.69.0.0/17"]}`), 0644))
+
+	options := &Options{RangesFile: path}
+	options.AddStaticRanges("edge", "10.10.0.0/16").DisableProviders("azure")
+	client, err := NewWithOptions(options)
+	require.Nil(t, err)
+	require.Len(t, client.Ranges(), 2)
+
+	found, provider, _ := client.Check(net.ParseIP("10.10.1.1"))
+	require.True(t, found)
+	require.Equal(t, "edge", provider)
+	found, _, _ = client.Check(net.ParseIP("13.69.0.1"))
+	require.False(t, found)
+
+	require.Nil(t, client.AddProvider(NewStaticProvider("imperva", "45.60.0.0/16")))
+	found, provider, _ = client.Check(net.ParseIP("45.60.1.1"))
+	require.True(t, found)
+	require.Equal(t, "imperva", provider)
+
+	client.RemoveProvider("imperva")
+	found, _, _ = client.Check(net.ParseIP("45.60.1.1"))
+	require.False(t, found)
+
+	// providers added again replace the registered ones
+	client.AddRanges("edge", "10.20.0.0/16")
+	client.AddRanges("edge", "10.30.0.0/16")
+	require.Nil(t, client.AddProvider(NewStaticProvider("imperva", "45.61.0.0/16")))
+	require.Len(t, options.Providers, 2)
+	require.Nil(t, client.Refresh())
+	require.Equal(t, []string{"10.30.0.0/16"}, client.Ranges()["edge"])
+	require.Equal(t, []string{"45.61.0.0/16"}, client.Ranges()["imperva"])
+	var edges int
+	for _, provider := range client.Report().Providers {
+		if provider.Name == "edge" {
+			edges++
+		}
+	}
+	require.Equal(t, 1, edges)
+}
+
+func TestIsProviderEnabled(t *testing.T) {
+	options := (&Options{}).EnableProviders("cloudflare", "fastly").DisableProviders("fastly")
+	require.True(t, options.IsProviderEnabled("cloudflare"))
+	require.False(t, options.IsProviderEnabled("fastly"))
+	require.False(t, options.IsProviderEnabled("azure"))
+}
+
+func TestPartialProviderFailure(t *testing.T) {
+	options := &Options{
+		EnabledProviders: []string{"edge", "flaky", "slow"},
+		ProviderTimeout:  100 * time.Millisecond,
+	}
+	options.AddStaticRanges("edge", "10.10.0.0/16")
+	options.RegisterProvider(
+		NewProvider("flaky", func(*http.Client, *Options) ([]string, error) {
+			return nil, errors.New("service unavailable")
+		}),
+		NewProvider("slow", func(*http.Client, *Options) ([]string, error) {
+			time.Sleep(time.Second)
+			return []string{"10.20.0.0/16"}, nil
+		}),
+	)
+
+	start := time.Now()
+	client, err := NewWithOptions(options)
+	require.Nil(t, err)
+	require.Less(t, time.Since(start), time.Second)
+
+	report := client.Report()
+	require.Equal(t, []string{"edge"}, report.Loaded())
+	require.Len(t, report.Failed(), 2)
+	require.Equal(t, 1, report.Providers[0].CIDRs)
+	require.NotNil(t, report.Err())
+
+	options.FailOnProviderError = true
+	_, err = NewWithOptions(options)
+	require.NotNil(t, err)
+}
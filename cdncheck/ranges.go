@@ -1,7 +1,10 @@
 package cdncheck
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"regexp"
 	"strings"
@@ -9,62 +12,84 @@ import (
 	jsoniter "github.com/json-iterator/go"
 )
 
-var cidrRegex = regexp.MustCompile(`[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\/[0-9]{1,3}`)
+var (
+	cidrRegex     = regexp.MustCompile(`[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\/[0-9]{1,3}`)
+	cidrIPv6Regex = regexp.MustCompile(`(?:[0-9a-fA-F]{0,4}:){2,7}[0-9a-fA-F]{0,4}\/[0-9]{1,3}`)
+)
 
 type scraperFunc func(httpClient *http.Client) ([]string, error)
 type scraperWithOptionsFunc func(httpClient *http.Client, options *Options) ([]string, error)
 
 // scrapeAzure scrapes Microsoft Azure firewall's CIDR ranges from their datacenter
 func scrapeAzure(httpClient *http.Client) ([]string, error) {
-	resp, err := httpClient.Get("https://download.microsoft.com/download/0/1/8/018E208D-54F8-44CD-AA26-CD7BC9524A8C/PublicIPs_20200824.xml")
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	data, err := ioutil.ReadAll(resp.Body)
+	req, err := http.NewRequest(http.MethodGet, "https://download.microsoft.com/download/0/1/8/018E208D-54F8-44CD-AA26-CD7BC9524A8C/PublicIPs_20200824.xml", nil)
 	if err != nil {
 		return nil, err
 	}
-	body := string(data)
-
-	cidrs := cidrRegex.FindAllString(body, -1)
-	return cidrs, nil
+	return fetchCIDRs(httpClient, req)
 }
 
-// scrapeCloudFront scrapes CloudFront firewall's CIDR ranges from their API
+// scrapeCloudFront scrapes CloudFront firewall's CIDR ranges from their API.
+// The CloudFront list only carries IPv4 ranges, so the IPv6 prefixes are taken
+// from the CLOUDFRONT service entries of the AWS ip-ranges feed
+// (ip-ranges.amazonaws.com). When that feed fails only the IPv4 ranges are returned.
 func scrapeCloudFront(httpClient *http.Client) ([]string, error) {
-	resp, err := httpClient.Get("https://d7uri8nf7uskq.cloudfront.net/tools/list-cloudfront-ips")
+	req, err := http.NewRequest(http.MethodGet, "https://d7uri8nf7uskq.cloudfront.net/tools/list-cloudfront-ips", nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	data, err := ioutil.ReadAll(resp.Body)
+	cidrs, err := fetchCIDRs(httpClient, req)
 	if err != nil {
 		return nil, err
 	}
-	body := string(data)
-
-	cidrs := cidrRegex.FindAllString(body, -1)
+	if ipv6, err := scrapeCloudFrontIPv6(httpClient); err == nil {
+		cidrs = append(cidrs, ipv6...)
+	}
 	return cidrs, nil
 }
 
-// scrapeCloudflare scrapes cloudflare firewall's CIDR ranges from their API
-func scrapeCloudflare(httpClient *http.Client) ([]string, error) {
-	resp, err := httpClient.Get("https://www.cloudflare.com/ips-v4")
+// scrapeCloudFrontIPv6 returns the IPv6 prefixes of the CLOUDFRONT service from the AWS ip-ranges feed
+func scrapeCloudFrontIPv6(httpClient *http.Client) ([]string, error) {
+	req, err := http.NewRequest(http.MethodGet, "https://ip-ranges.amazonaws.com/ip-ranges.json", nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	data, err := ioutil.ReadAll(resp.Body)
+	data, err := fetchBody(httpClient, req)
 	if err != nil {
 		return nil, err
 	}
-	body := string(data)
+	var awsRanges struct {
+		IPv6Prefixes []struct {
+			Prefix  string `json:"ipv6_prefix"`
+			Service string `json:"service"`
+		} `json:"ipv6_prefixes"`
+	}
+	if err := jsoniter.Unmarshal(data, &awsRanges); err != nil {
+		return nil, err
+	}
+	var cidrs []string
+	for _, prefix := range awsRanges.IPv6Prefixes {
+		if prefix.Service == "CLOUDFRONT" {
+			cidrs = append(cidrs, prefix.Prefix)
+		}
+	}
+	return cidrs, nil
+}
 
-	cidrs := cidrRegex.FindAllString(body, -1)
+// scrapeCloudflare scrapes cloudflare firewall's CIDR ranges from their API
+func scrapeCloudflare(httpClient *http.Client) ([]string, error) {
+	var cidrs []string
+	for _, URL := range []string{"https://www.cloudflare.com/ips-v4", "https://www.cloudflare.com/ips-v6"} {
+		req, err := http.NewRequest(http.MethodGet, URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		found, err := fetchCIDRs(httpClient, req)
+		if err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, found...)
+	}
 	return cidrs, nil
 }
 
@@ -76,20 +101,7 @@ func scrapeIncapsula(httpClient *http.Client) ([]string, error) {
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	body := string(data)
-
-	cidrs := cidrRegex.FindAllString(body, -1)
-	return cidrs, nil
+	return fetchCIDRs(httpClient, req)
 }
 
 // scrapeAkamai scrapes akamai firewall's CIDR ranges from ipinfo
@@ -98,20 +110,7 @@ func scrapeAkamai(httpClient *http.Client, options *Options) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	body := string(data)
-
-	cidrs := cidrRegex.FindAllString(body, -1)
-	return cidrs, nil
+	return fetchCIDRs(httpClient, req)
 }
 
 // scrapeSucuri scrapes sucuri firewall's CIDR ranges from ipinfo
@@ -120,73 +119,86 @@ func scrapeSucuri(httpClient *http.Client, options *Options) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp, err := httpClient.Do(req)
+	return fetchCIDRs(httpClient, req)
+}
+
+func scrapeFastly(httpClient *http.Client) ([]string, error) {
+	req, err := http.NewRequest(http.MethodGet, "https://api.fastly.com/public-ip-list", nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return fetchCIDRs(httpClient, req)
+}
 
-	data, err := ioutil.ReadAll(resp.Body)
+// scrapeLeaseweb scrapes leaseweb firewall's CIDR ranges from ipinfo
+func scrapeLeaseweb(httpClient *http.Client, options *Options) ([]string, error) {
+	req, err := makeReqWithAuth(http.MethodGet, "https://ipinfo.io/AS60626", "Authorization", "Bearer "+options.IPInfoToken)
 	if err != nil {
 		return nil, err
 	}
-	body := string(data)
-
-	cidrs := cidrRegex.FindAllString(body, -1)
-	return cidrs, nil
+	return fetchCIDRs(httpClient, req)
 }
 
-func scrapeFastly(httpClient *http.Client) ([]string, error) {
-	resp, err := httpClient.Get("https://api.fastly.com/public-ip-list")
+func scrapeProjectDiscovery(httpClient *http.Client) (map[string][]string, error) {
+	req, err := http.NewRequest(http.MethodGet, "https://cdn.nuclei.sh", nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	data, err := ioutil.ReadAll(resp.Body)
+	body, err := fetchBody(httpClient, req)
 	if err != nil {
 		return nil, err
 	}
-	body := string(data)
-
-	cidrs := cidrRegex.FindAllString(body, -1)
-	return cidrs, nil
-}
 
-// scrapeLeaseweb scrapes leaseweb firewall's CIDR ranges from ipinfo
-func scrapeLeaseweb(httpClient *http.Client, options *Options) ([]string, error) {
-	req, err := makeReqWithAuth(http.MethodGet, "https://ipinfo.io/AS60626", "Authorization", "Bearer "+options.IPInfoToken)
-	if err != nil {
+	var data map[string][]string
+	if err := jsoniter.Unmarshal(body, &data); err != nil {
 		return nil, err
 	}
+	if len(data) == 0 {
+		return nil, errNoRanges
+	}
+	return data, nil
+}
+
+// errNoRanges is returned when a provider answers without any range
+var errNoRanges = errors.New("no ranges found in the response")
+
+// fetchBody sends req and returns the response body. Non-2xx responses fail,
+// so the 403 or 429 pages served to a banned IP are not taken for empty ranges.
+func fetchBody(httpClient *http.Client, req *http.Request) ([]byte, error) {
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s: unexpected status %s", req.URL, resp.Status)
 	}
-	body := string(data)
-
-	cidrs := cidrRegex.FindAllString(body, -1)
-	return cidrs, nil
+	return ioutil.ReadAll(resp.Body)
 }
 
-func scrapeProjectDiscovery(httpClient *http.Client) (map[string][]string, error) {
-	resp, err := httpClient.Get("https://cdn.nuclei.sh")
+// fetchCIDRs returns the CIDRs found in the response to req, failing when there are none
+func fetchCIDRs(httpClient *http.Client, req *http.Request) ([]string, error) {
+	data, err := fetchBody(httpClient, req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	cidrs := extractCIDRs(string(data))
+	if len(cidrs) == 0 {
+		return nil, fmt.Errorf("%s: %w", req.URL, errNoRanges)
+	}
+	return cidrs, nil
+}
 
-	var data map[string][]string
-	if err := jsoniter.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
+// extractCIDRs returns every IPv4 and IPv6 CIDR found in body
+func extractCIDRs(body string) []string {
+	cidrs := cidrRegex.FindAllString(body, -1)
+	for _, cidr := range cidrIPv6Regex.FindAllString(body, -1) {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.IP.To4() == nil {
+			cidrs = append(cidrs, cidr)
+		}
 	}
-	return data, nil
+	return cidrs
 }
 
 func makeReqWithAuth(method, URL, headerName, bearerValue string) (*http.Request, error) {
@@ -0,0 +1,128 @@
+package cdncheck
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// cannedPayloads maps provider endpoints to the responses served by the test server
+var cannedPayloads = map[string]string{
+	"www.cloudflare.com/ips-v4":                              "173.245.48.0/20\n103.21.244.0/22\n",
+	"www.cloudflare.com/ips-v6":                              "2400:cb00::/32\n2606:4700::/32\n",
+	"d7uri8nf7uskq.cloudfront.net/tools/list-cloudfront-ips": `{"CLOUDFRONT_GLOBAL_IP_LIST":["120.52.22.96/27"],"CLOUDFRONT_REGIONAL_EDGE_IP_LIST":["13.113.196.64/26"]}`,
+	"ip-ranges.amazonaws.com/ip-ranges.json":                 `{"ipv6_prefixes":[{"ipv6_prefix":"2600:9000::/28","service":"CLOUDFRONT"},{"ipv6_prefix":"2a05:d07a:a000::/40","service":"S3"}]}`,
+	"api.fastly.com/public-ip-list":                          `{"addresses":["23.235.32.0/20"],"ipv6_addresses":["2a04:4e40::/32","2a04:4e42::/32"]}`,
+	"download.microsoft.com/download/0/1/8/018E208D-54F8-44CD-AA26-CD7BC9524A8C/PublicIPs_20200824.xml": `<AzurePublicIpAddresses><Region Name="europewest"><IpRange Subnet="13.69.0.0/17" /><IpRange Subnet="2603:1020:200::/46" /></Region></AzurePublicIpAddresses>`,
+	"my.incapsula.com/api/integration/v1/ips":                                                           "199.83.128.0/21\n2a02:e980::/29\n",
+}
+
+// redirectTransport sends every request to the test server, keeping the original host and path
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Path = "/" + req.URL.Host + req.URL.Path
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newCannedHTTPClient(t *testing.T) *http.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, ok := cannedPayloads[r.URL.Path[1:]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(payload))
+	}))
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	return &http.Client{Transport: &redirectTransport{target: target}}
+}
+
+func TestExtractCIDRs(t *testing.T) {
+	cidrs := extractCIDRs(`<a href="/AS12222/2.16.0.0/13">2.16.0.0/13</a> <a href="/AS12222/2a02:26f0::/32">2a02:26f0::/32</a> at 12:30:45/1`)
+	require.ElementsMatch(t, []string{"2.16.0.0/13", "2.16.0.0/13", "2a02:26f0::/32", "2a02:26f0::/32"}, cidrs)
+}
+
+func TestScrapersIPv6(t *testing.T) {
+	httpClient := newCannedHTTPClient(t)
+
+	tests := []struct {
+		provider string
+		scraper  scraperFunc
+		ipv4     string
+		ipv6     string
+	}{
+		{"cloudflare", scrapeCloudflare, "173.245.48.1", "2606:4700::1111"},
+		{"cloudfront", scrapeCloudFront, "13.113.196.70", "2600:9000:2000::1"},
+		{"fastly", scrapeFastly, "23.235.32.1", "2a04:4e42::1"},
+		{"azure", scrapeAzure, "13.69.1.1", "2603:1020:200::1"},
+		{"incapsula", scrapeIncapsula, "199.83.128.1", "2a02:e980::1"},
+	}
+	ranges := make(map[string][]string)
+	for _, test := range tests {
+		cidrs, err := test.scraper(httpClient)
+		require.Nil(t, err, test.provider)
+		ranges[test.provider] = cidrs
+	}
+	require.NotContains(t, ranges["cloudfront"], "2a05:d07a:a000::/40")
+
+	client := &Client{Options: &Options{}}
+	client.data.Store(newRangeData(ranges, client.Options.categories(), &Report{}))
+	for _, test := range tests {
+		for _, ip := range []string{test.ipv4, test.ipv6} {
+			found, provider, err := client.Check(net.ParseIP(ip))
+			require.Nil(t, err)
+			require.True(t, found, ip)
+			require.Equal(t, test.provider, provider, ip)
+		}
+	}
+	found, _, err := client.Check(net.ParseIP("2001:db8::1"))
+	require.Nil(t, err)
+	require.False(t, found)
+}
+
+func TestScraperErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api.fastly.com/public-ip-list" {
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("<html>maintenance</html>"))
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	httpClient := &http.Client{Transport: &redirectTransport{target: target}}
+
+	_, err := scrapeFastly(httpClient)
+	require.ErrorContains(t, err, "429")
+	_, err = scrapeAzure(httpClient)
+	require.ErrorIs(t, err, errNoRanges)
+}
+
+func TestScrapeCloudFrontWithoutIPv6(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, ok := cannedPayloads[r.URL.Path[1:]]
+		if !ok || r.URL.Path == "/ip-ranges.amazonaws.com/ip-ranges.json" {
+			http.Error(w, "Access denied", http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(payload))
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	cidrs, err := scrapeCloudFront(&http.Client{Transport: &redirectTransport{target: target}})
+	require.Nil(t, err)
+	require.Equal(t, []string{"120.52.22.96/27", "13.113.196.64/26"}, cidrs)
+}
@@ -0,0 +1,68 @@
+package cdncheck
+
+import (
+	"context"
+	"time"
+)
+
+// Refresh fetches the ranges again and atomically replaces the loaded ones.
+// Concurrent checks keep using the previous ranges until the new ones are built.
+// Providers failing to refresh keep their previous ranges.
+// With Options.CacheDir, the cached ranges are reused until Options.CacheTTL
+// expires, so a refresh only fetches the providers whose cache entry is stale.
+func (c *Client) Refresh() error {
+	c.mu.Lock()
+	data, err := c.getCDNData(c.data.Load())
+	if err == nil {
+		c.data.Store(data)
+		err = data.report.Err()
+	}
+	c.mu.Unlock()
+
+	c.refreshMu.Lock()
+	c.lastRefresh = time.Now()
+	c.lastRefreshErr = err
+	c.refreshMu.Unlock()
+	return err
+}
+
+// AutoRefresh refreshes the ranges every interval until ctx is done.
+// It blocks, so it is usually started in its own goroutine.
+// A non-positive interval refreshes once per cache TTL. With Options.CacheDir,
+// an interval shorter than the cache TTL only picks up the entries other
+// processes fetched, the providers are fetched once the TTL expires (see Refresh).
+func (c *Client) AutoRefresh(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = c.Options.CacheTTL
+	}
+	if interval <= 0 {
+		interval = DefaultCacheTTL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = c.Refresh()
+		}
+	}
+}
+
+// LastRefresh returns the time of the last load of the ranges
+func (c *Client) LastRefresh() time.Time {
+	c.refreshMu.RLock()
+	defer c.refreshMu.RUnlock()
+
+	return c.lastRefresh
+}
+
+// LastRefreshError returns the error of the last refresh, nil when it fully succeeded
+func (c *Client) LastRefreshError() error {
+	c.refreshMu.RLock()
+	defer c.refreshMu.RUnlock()
+
+	return c.lastRefreshErr
+}
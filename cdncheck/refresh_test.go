@@ -0,0 +1,74 @@
+package cdncheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoRefresh(t *testing.T) {
+	var calls int32
+	options := &Options{EnabledProviders: []string{"rotating"}}
+	options.RegisterProvider(NewProvider("rotating", func(*http.Client, *Options) ([]string, error) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			return []string{"10.0.0.0/16"}, nil
+		case 2:
+			return nil, errors.New("endpoint down")
+		default:
+			return []string{"10.1.0.0/16"}, nil
+		}
+	}))
+	client, err := NewWithOptions(options)
+	require.Nil(t, err)
+	loaded := client.LastRefresh()
+
+	// a failed refresh keeps the previous ranges
+	require.NotNil(t, client.Refresh())
+	require.NotNil(t, client.LastRefreshError())
+	require.True(t, client.LastRefresh().After(loaded))
+	found, _, _ := client.Check(net.ParseIP("10.0.0.1"))
+	require.True(t, found)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		client.AutoRefresh(ctx, 10*time.Millisecond)
+	}()
+	go func() {
+		defer wg.Done()
+		for ctx.Err() == nil {
+			_, _, _ = client.Check(net.ParseIP("10.1.0.1"))
+		}
+	}()
+
+	require.Eventually(t, func() bool {
+		found, _, _ := client.Check(net.ParseIP("10.1.0.1"))
+		return found
+	}, time.Second, 5*time.Millisecond)
+	require.Nil(t, client.LastRefreshError())
+	cancel()
+	wg.Wait()
+
+	found, _, _ = client.Check(net.ParseIP("10.0.0.1"))
+	require.False(t, found)
+
+	// a non-positive interval falls back to the cache TTL
+	ctx, cancel = context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.AutoRefresh(ctx, 0)
+		close(done)
+	}()
+	cancel()
+	<-done
+}
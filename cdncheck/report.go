@@ -0,0 +1,63 @@
+package cdncheck
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// ProviderReport describes the outcome of loading the ranges of a provider
+type ProviderReport struct {
+	Name     string
+	CIDRs    int
+	Duration time.Duration
+	Err      error
+	// Source is where the ranges were loaded from
+	Source Source
+	// FetchedAt is when the ranges were fetched from the provider, zero when unknown
+	FetchedAt time.Time
+}
+
+// Report describes which providers were loaded by the client and which failed
+type Report struct {
+	Providers []ProviderReport
+}
+
+// Loaded returns the names of the providers whose ranges were loaded
+func (r *Report) Loaded() []string {
+	var names []string
+	for _, provider := range r.Providers {
+		if provider.Err == nil {
+			names = append(names, provider.Name)
+		}
+	}
+	return names
+}
+
+// Failed returns the reports of the providers that could not be loaded
+func (r *Report) Failed() []ProviderReport {
+	var failed []ProviderReport
+	for _, provider := range r.Providers {
+		if provider.Err != nil {
+			failed = append(failed, provider)
+		}
+	}
+	return failed
+}
+
+// Err returns the combined errors of the failed providers
+func (r *Report) Err() error {
+	var err error
+	for _, provider := range r.Failed() {
+		err = multierr.Append(err, fmt.Errorf("%s: %w", provider.Name, provider.Err))
+	}
+	return err
+}
+
+func (r *Report) sort() {
+	sort.Slice(r.Providers, func(i, j int) bool {
+		return r.Providers[i].Name < r.Providers[j].Name
+	})
+}
@@ -0,0 +1,247 @@
+package goevent
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// What Publish does when the queue of a topic is full
+type OverflowPolicy int
+
+const (
+	// Block the publisher until the queue has room
+	OverflowBlock OverflowPolicy = iota
+	// Drop the oldest queued event to make room
+	OverflowDropOldest
+	// Drop the published event
+	OverflowDropNewest
+)
+
+var (
+	// Returned by Publish once the events are closed
+	ErrClosed = errors.New("goevent: events closed")
+	// Returned by Publish when the event is dropped by OverflowDropNewest
+	ErrDropped = errors.New("goevent: queue full, event dropped")
+)
+
+// Statistics of the queue of a topic
+type QueueStats struct {
+	Depth     int
+	Capacity  int
+	Published uint64
+	Dropped   uint64
+	Processed uint64
+}
+
+// Default size and workers of a topic queue
+const (
+	defaultQueueSize    = 128
+	defaultQueueWorkers = 1
+)
+
+// Buffered queues of Publish, one per topic, drained by background workers
+type asyncDispatcher struct {
+	events *events
+	//Held for reading while a publish starts, for writing while closing
+	mu sync.RWMutex
+	//Guard the queues map
+	queuesMu sync.RWMutex
+	queues   map[string]*asyncQueue
+	size     int
+	workers  int
+	policy   OverflowPolicy
+	closed   bool
+	//Closed by Close to release the publishers blocked on a full queue
+	done chan struct{}
+	//Publishes sending to a queue, the queues are closed once they returned
+	sending sync.WaitGroup
+	wg      sync.WaitGroup
+}
+
+type asyncQueue struct {
+	ch        chan asyncEvent
+	published atomic.Uint64
+	dropped   atomic.Uint64
+	processed atomic.Uint64
+}
+
+func newAsyncDispatcher(ev *events) *asyncDispatcher {
+	return &asyncDispatcher{
+		events:  ev,
+		queues:  make(map[string]*asyncQueue),
+		size:    defaultQueueSize,
+		workers: defaultQueueWorkers,
+		done:    make(chan struct{}),
+	}
+}
+
+// Set the size, the number of workers and the overflow policy of the topic queues.
+// It applies to the queues created after the call.
+// Every topic published has its own queue and workers until Close, so publish
+// to a bounded set of topics: rather than "scan.<host>", publish the host to "scan".
+func (this *events) ConfAsync(size int, workers int, policy OverflowPolicy) {
+	async := this.async
+	async.mu.Lock()
+	defer async.mu.Unlock()
+
+	if size > 0 {
+		async.size = size
+	}
+	if workers > 0 {
+		async.workers = workers
+	}
+	async.policy = policy
+}
+
+/**
+ * Publish args to the topic without waiting for its events
+ * The events of the topic (see Trigger) run with args in background workers,
+ * their errors are given to the error hook.
+ * When the queue of the topic is full the overflow policy applies.
+ * With a store (see Persist) the event is recorded first and acknowledged once its
+ * events succeeded, a dropped event stays in the store until it is replayed.
+ * A publisher blocked by OverflowBlock returns ErrClosed when the events are closed.
+ */
+func (this *events) Publish(name string, args ...Arguments) error {
+	async := this.async
+	async.mu.RLock()
+	if async.closed {
+		async.mu.RUnlock()
+		return ErrClosed
+	}
+	async.sending.Add(1)
+	defer async.sending.Done()
+	queue := async.queue(name)
+	policy := async.policy
+	async.mu.RUnlock()
+
+	id, err := this.persist(name, args)
+	if err != nil {
+		return err
+	}
+	event := asyncEvent{args, id}
+	queue.published.Add(1)
+
+	switch policy {
+	case OverflowDropNewest:
+		select {
+		case queue.ch <- event:
+		default:
+			queue.dropped.Add(1)
+			return ErrDropped
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case queue.ch <- event:
+				return nil
+			default:
+			}
+			select {
+			case <-queue.ch:
+				queue.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case queue.ch <- event:
+		case <-async.done:
+			queue.dropped.Add(1)
+			return ErrClosed
+		}
+	}
+	return nil
+}
+
+// Return the queue of the topic, creating it and its workers on first use.
+// The caller must hold the read lock of the dispatcher.
+func (this *asyncDispatcher) queue(name string) *asyncQueue {
+	if queue := this.lookup(name); queue != nil {
+		return queue
+	}
+
+	this.queuesMu.Lock()
+	defer this.queuesMu.Unlock()
+	if queue, ok := this.queues[name]; ok {
+		return queue
+	}
+	queue := &asyncQueue{ch: make(chan asyncEvent, this.size)}
+	this.queues[name] = queue
+	for i := 0; i < this.workers; i++ {
+		this.wg.Add(1)
+		go this.drain(name, queue)
+	}
+	return queue
+}
+
+func (this *asyncDispatcher) lookup(name string) *asyncQueue {
+	this.queuesMu.RLock()
+	defer this.queuesMu.RUnlock()
+
+	return this.queues[name]
+}
+
+// A published event, id is its record in the store when persisted
+type asyncEvent struct {
+	args []Arguments
+	id   uint64
+}
+
+// Run the events of the topic for every queued args until the queue is closed
+func (this *asyncDispatcher) drain(name string, queue *asyncQueue) {
+	defer this.wg.Done()
+
+	for event := range queue.ch {
+		err := this.events.trigger([]string{name}, event.args)
+		if event.id != 0 {
+			this.events.ack(event.id, err)
+		}
+		queue.processed.Add(1)
+	}
+}
+
+// Return the statistics of every topic queue
+func (this *events) QueueStats() map[string]QueueStats {
+	async := this.async
+	async.queuesMu.RLock()
+	defer async.queuesMu.RUnlock()
+
+	stats := make(map[string]QueueStats, len(async.queues))
+	for name, queue := range async.queues {
+		stats[name] = QueueStats{
+			Depth:     len(queue.ch),
+			Capacity:  cap(queue.ch),
+			Published: queue.published.Load(),
+			Dropped:   queue.dropped.Load(),
+			Processed: queue.processed.Load(),
+		}
+	}
+	return stats
+}
+
+// Stop accepting published events and wait until the queued ones have run.
+// The publishers blocked on a full queue are released with ErrClosed.
+func (this *events) Close() error {
+	async := this.async
+	async.mu.Lock()
+	if async.closed {
+		async.mu.Unlock()
+		return ErrClosed
+	}
+	async.closed = true
+	close(async.done)
+	async.mu.Unlock()
+
+	//No publish sends to the queues anymore once the started ones returned
+	async.sending.Wait()
+	async.queuesMu.RLock()
+	for _, queue := range async.queues {
+		close(queue.ch)
+	}
+	async.queuesMu.RUnlock()
+
+	async.wg.Wait()
+	return nil
+}
@@ -0,0 +1,91 @@
+package goevent
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublish(t *testing.T) {
+	ev := Classic()
+	var sum int64
+	ev.On("scan.result", func(n int) { atomic.AddInt64(&sum, int64(n)) })
+
+	for i := 1; i <= 100; i++ {
+		require.NoError(t, ev.Publish("scan.result", i))
+	}
+	require.NoError(t, ev.Close())
+	require.Equal(t, int64(5050), atomic.LoadInt64(&sum))
+	require.Equal(t, uint64(100), ev.QueueStats()["scan.result"].Processed)
+	require.ErrorIs(t, ev.Publish("scan.result", 1), ErrClosed)
+}
+
+func TestPublishOverflow(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowDropNewest, OverflowDropOldest} {
+		ev := Classic()
+		ev.ConfAsync(2, 1, policy)
+		release := make(chan struct{})
+		var seen []int
+		ev.On("slow", func(n int) {
+			<-release
+			seen = append(seen, n)
+		})
+
+		// the worker holds the first event, the next two fill the queue
+		require.NoError(t, ev.Publish("slow", 0))
+		require.Eventually(t, func() bool { return ev.QueueStats()["slow"].Depth == 0 }, time.Second, time.Millisecond)
+		require.NoError(t, ev.Publish("slow", 1))
+		require.NoError(t, ev.Publish("slow", 2))
+
+		err := ev.Publish("slow", 3)
+		stats := ev.QueueStats()["slow"]
+		require.Equal(t, 2, stats.Depth)
+		require.Equal(t, uint64(1), stats.Dropped)
+
+		close(release)
+		require.NoError(t, ev.Close())
+		if policy == OverflowDropNewest {
+			require.ErrorIs(t, err, ErrDropped)
+			require.Equal(t, []int{0, 1, 2}, seen)
+		} else {
+			require.NoError(t, err)
+			require.Equal(t, []int{0, 2, 3}, seen)
+		}
+	}
+}
+
+func TestCloseBlockedPublish(t *testing.T) {
+	ev := Classic()
+	ev.ConfAsync(1, 1, OverflowBlock)
+	release := make(chan struct{})
+	published := make(chan error, 2)
+	ev.On("a", func() {
+		<-release
+		published <- ev.Publish("b")
+	})
+
+	// the worker holds the first event, the second fills the queue, the third blocks
+	require.NoError(t, ev.Publish("a"))
+	require.Eventually(t, func() bool { return ev.QueueStats()["a"].Depth == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, ev.Publish("a"))
+	blocked := make(chan error, 1)
+	go func() { blocked <- ev.Publish("a") }()
+	require.Eventually(t, func() bool { return ev.QueueStats()["a"].Published == 3 }, time.Second, time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() { closed <- ev.Close() }()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("close did not return")
+	}
+	require.ErrorIs(t, <-blocked, ErrClosed)
+	require.ErrorIs(t, <-published, ErrClosed)
+	require.ErrorIs(t, <-published, ErrClosed)
+}
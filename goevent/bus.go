@@ -0,0 +1,77 @@
+package goevent
+
+import (
+	"sync"
+
+	"go.uber.org/multierr"
+)
+
+// Bus is a typed event bus.
+// Handlers are called directly, without reflection, so type errors are
+// reported at compile time instead of panicking at runtime.
+type Bus[T any] struct {
+	mu         sync.RWMutex
+	handlers   []busHandler[T]
+	lastHandle Handle
+}
+
+type busHandler[T any] struct {
+	handle Handle
+	fn     func(T) error
+}
+
+// Create a new typed event bus
+func NewBus[T any]() *Bus[T] {
+	return &Bus[T]{}
+}
+
+// Subscribe fn to the events published on the bus.
+// Return the handle used to unsubscribe it.
+func (this *Bus[T]) Subscribe(fn func(T) error) Handle {
+	if fn == nil {
+		return 0
+	}
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	this.lastHandle++
+	this.handlers = append(this.handlers, busHandler[T]{this.lastHandle, fn})
+	return this.lastHandle
+}
+
+// Unsubscribe the handler of the handle returned by Subscribe
+func (this *Bus[T]) Unsubscribe(handle Handle) bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	for i, h := range this.handlers {
+		if h.handle == handle {
+			this.handlers = append(this.handlers[:i:i], this.handlers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Publish the event to every handler in subscription order.
+// Return the errors of all the handlers combined.
+func (this *Bus[T]) Publish(event T) error {
+	this.mu.RLock()
+	handlers := this.handlers
+	this.mu.RUnlock()
+
+	var err error
+	for _, h := range handlers {
+		err = multierr.Append(err, h.fn(event))
+	}
+	return err
+}
+
+// Len return the number of subscribed handlers
+func (this *Bus[T]) Len() int {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+
+	return len(this.handlers)
+}
@@ -0,0 +1,64 @@
+package goevent
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
+)
+
+type userCreated struct {
+	ID   int
+	Name string
+}
+
+func TestBus(t *testing.T) {
+	bus := NewBus[userCreated]()
+	var names []string
+	bus.Subscribe(func(e userCreated) error {
+		names = append(names, e.Name)
+		return nil
+	})
+	failing := bus.Subscribe(func(e userCreated) error {
+		return errors.New("mailer down")
+	})
+	bus.Subscribe(func(e userCreated) error {
+		return errors.New("audit down")
+	})
+
+	err := bus.Publish(userCreated{1, "alice"})
+	require.Len(t, multierr.Errors(err), 2)
+	require.Equal(t, []string{"alice"}, names)
+
+	require.True(t, bus.Unsubscribe(failing))
+	require.Equal(t, 2, bus.Len())
+	require.EqualError(t, bus.Publish(userCreated{2, "bob"}), "audit down")
+	require.Equal(t, []string{"alice", "bob"}, names)
+}
+
+func BenchmarkBusPublish(b *testing.B) {
+	bus := NewBus[userCreated]()
+	var sum int
+	bus.Subscribe(func(e userCreated) error {
+		sum += e.ID
+		return nil
+	})
+	event := userCreated{1, "alice"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = bus.Publish(event)
+	}
+}
+
+func BenchmarkEventItemExec(b *testing.B) {
+	var sum int
+	item := NewEvent(func(e userCreated) {
+		sum += e.ID
+	}, nil)
+	event := userCreated{1, "alice"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		item.exec(event)
+	}
+}
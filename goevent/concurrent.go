@@ -1,14 +1,21 @@
 package goevent
 
-import "reflect"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+)
 
 type Concurrent interface {
-	gofunc(args ...Arguments)
-	wait()
 	end(fn EventFunc, args ...Arguments)
-	emit()
+	emit() error
 }
 
+// The concurrent queue, it is guarded by the lock of the events owning it.
 type concurrent struct {
 	*channelManager
 	//Concurrent events queue
@@ -17,23 +24,46 @@ type concurrent struct {
 	waited       bool
 	endFn        *eventItem
 	currentParam []Arguments
+	errorHook    ErrorHook
+	//Time limit of every event, zero means no limit
+	timeout     time.Duration
+	middlewares []Middleware
 }
 
 type channelManager struct {
+	//Number of events running at the same time
 	chNumber int
-	ch       chan int
+}
+
+// A snapshot of the concurrent queue taken when emitting,
+// so the queue can be changed while its events are running.
+type concurrentRun struct {
+	loop        []*eventItem
+	params      [][]Arguments
+	waited      bool
+	endFn       *eventItem
+	endParam    []Arguments
+	errorHook   ErrorHook
+	workers     int
+	timeout     time.Duration
+	middlewares []Middleware
+	//Closed when all the events have finished
+	done chan struct{}
+	//Errors of the events running in gorountine
+	errs  error
+	errMu sync.Mutex
 }
 
 func NewConcurrent(chNum int) *concurrent {
 	loop := make([]*eventItem, 0)
 	cur := make([]Arguments, 0)
 	endFn := NewEvent(initFn, cur)
-	return &concurrent{NewChannelManager(chNum), loop, true, endFn, cur}
+	endFn.name = "end"
+	return &concurrent{channelManager: NewChannelManager(chNum), loop: loop, waited: true, endFn: endFn, currentParam: cur}
 }
 
 func NewChannelManager(chNum int) *channelManager {
-	ch := make(chan int, chNum)
-	return &channelManager{chNum, ch}
+	return &channelManager{chNum}
 }
 
 // bind event to concurrent queue.
@@ -43,6 +73,7 @@ func (this *concurrent) on(fn EventFunc, args ...Arguments) *concurrent {
 	}
 
 	item := NewEvent(fn, args)
+	item.name = "concurrent"
 
 	this.loop = append(this.loop, item)
 	this.currentParam = args
@@ -50,25 +81,68 @@ func (this *concurrent) on(fn EventFunc, args ...Arguments) *concurrent {
 }
 
 // Concurrent run the events that has been defined
-func (this *concurrent) gofunc(item *eventItem) {
+// The event fails with an error when ctx is done or the timeout expires,
+// events whose first parameter is a context.Context receive ctx to stop early.
+// The worker waits for the event to return anyway, so no more than chNumber
+// events are ever running.
+func (this *concurrentRun) gofunc(ctx context.Context, item *eventItem, param []Arguments) {
+	if this.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, this.timeout)
+		defer cancel()
+	}
 
-	//Set argument by current param If lenght of args equal zero.
-	if len(item.param) == 0 {
-		item.param = this.currentParam
+	result := make(chan error, 1)
+	go func() {
+		result <- runMiddlewares(this.middlewares, item.name, func() error {
+			return item.callContext(ctx, param...)
+		})
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil && !errors.Is(err, ErrStopPropagation) {
+			this.addError(item.name, err)
+		}
+	case <-ctx.Done():
+		this.addError(item.name, fmt.Errorf("event %s: %w", item.name, ctx.Err()))
+		<-result
 	}
-	argvs := getArgs(item.param...)
-	reflect.ValueOf(item.fn).Call(argvs[:item.len])
+}
+
+// Collect the error of an event running in gorountine
+func (this *concurrentRun) addError(name string, err error) {
+	this.errMu.Lock()
+	defer this.errMu.Unlock()
 
-	this.ch <- 1
+	this.errs = multierr.Append(this.errs, err)
+	if this.errorHook != nil {
+		this.errorHook(name, err)
+	}
 }
 
-// Wait all the goruountine finished.
+// Wait all the goruountine finished and run the last event.
 // If not finished will wait here.
-func (this *concurrent) wait() {
-	len := len(this.loop)
-	for i := 0; i < len; i++ {
-		<-this.ch
+// Return the errors of the finished events.
+func (this *concurrentRun) wait() error {
+	<-this.done
+
+	//running the last event
+	if err := this.endFn.call(this.endParam...); err != nil {
+		this.addError(this.endFn.name, err)
 	}
+
+	this.errMu.Lock()
+	defer this.errMu.Unlock()
+	return this.errs
+}
+
+// Remove all the concurrent events and the last event.
+func (this *concurrent) clear() {
+	this.loop = make([]*eventItem, 0)
+	this.currentParam = make([]Arguments, 0)
+	this.endFn = NewEvent(initFn, this.currentParam)
+	this.endFn.name = "end"
 }
 
 // Add the last event function called.
@@ -77,40 +151,76 @@ func (this *concurrent) end(fn EventFunc, args ...Arguments) {
 		return
 	}
 
-	this.endFn.fn = fn
-	this.endFn.param = args
+	this.endFn = NewEvent(fn, args)
+	this.endFn.name = "end"
 }
 
-// Emit all concurrent events
-func (this *concurrent) emit() error {
-
-	if len(this.loop) == 0 {
-		return nil
-	}
-
-	//invoke the events that was in the queue
-	for _, e := range this.loop {
-		go this.gofunc(e)
+// Take a snapshot of the queue to emit it.
+// The caller must hold the lock of the events.
+func (this *concurrent) snapshot() *concurrentRun {
+	run := &concurrentRun{
+		loop:        append([]*eventItem(nil), this.loop...),
+		params:      make([][]Arguments, len(this.loop)),
+		waited:      this.waited,
+		endFn:       this.endFn,
+		endParam:    this.endFn.param,
+		errorHook:   this.errorHook,
+		workers:     this.chNumber,
+		timeout:     this.timeout,
+		middlewares: this.middlewares,
+		done:        make(chan struct{}),
 	}
-
-	if this.waited {
-		this.wait()
+	for i, e := range this.loop {
+		//Set argument by current param If lenght of args equal zero.
+		run.params[i] = e.param
+		if len(e.param) == 0 {
+			run.params[i] = this.currentParam
+		}
 	}
+	return run
+}
 
-	//p(this.chNumber)
-
-	//running the last event
-	params := this.endFn.param
+// Emit all concurrent events
+func (this *concurrent) emit() error {
+	run := this.snapshot()
+	run.start(context.Background())
+	return run.wait()
+}
 
-	fn, ok := this.endFn.fn.(eventFunc)
-	if !ok {
-		return nil
+// Start the events of the snapshot on a pool of chNumber workers.
+// The events not started yet are skipped when ctx is done.
+func (this *concurrentRun) start(ctx context.Context) {
+	workers := this.workers
+	if workers <= 0 || workers > len(this.loop) {
+		workers = len(this.loop)
 	}
-	if len(params) == 0 {
-		fn()
-	} else {
-		fn(params...)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				this.gofunc(ctx, this.loop[i], this.params[i])
+			}
+		}()
 	}
 
-	return nil
+	go func() {
+		defer func() {
+			close(jobs)
+			wg.Wait()
+			close(this.done)
+		}()
+
+		for i := range this.loop {
+			select {
+			case <-ctx.Done():
+				this.addError("concurrent", fmt.Errorf("%d events not run: %w", len(this.loop)-i, ctx.Err()))
+				return
+			case jobs <- i:
+			}
+		}
+	}()
 }
@@ -1,9 +1,12 @@
 package goevent
 
+import "time"
+
 type config struct {
-	chNumber int  //parallel channel numbers
-	safe     int  //events running mod
-	setted   bool //every events can just set once.
+	chNumber int           //parallel channel numbers
+	safe     int           //events running mod
+	setted   bool          //every events can just set once.
+	timeout  time.Duration //time limit of every concurrent event
 }
 
 func newConf(chm int, safe int, setted bool) *config {
@@ -11,20 +14,38 @@ func newConf(chm int, safe int, setted bool) *config {
 	if chm <= 0 {
 		chm = 5
 	}
-	return &config{chm, safe, setted}
+	return &config{chNumber: chm, safe: safe, setted: setted}
 }
 
 // ======================events object =========================
 // setting the events object
 func (ev *events) Conf(chm int, safe int) {
+	ev.Lock()
+	defer ev.Unlock()
+
 	if ev.config.setted {
 		return
 	}
+	timeout := ev.config.timeout
 	ev.config = newConf(chm, safe, true)
+	ev.config.timeout = timeout
+	ev.flush()
+}
+
+// Set the time limit of every concurrent event, zero means no limit.
+// An event running longer is reported as failed, events whose first
+// parameter is a context.Context are also cancelled.
+// Its worker stays busy until it returns.
+func (ev *events) SetTimeout(timeout time.Duration) {
+	ev.Lock()
+	defer ev.Unlock()
+
+	ev.config.timeout = timeout
 	ev.flush()
 }
 
 // flush events by config
 func (ev *events) flush() {
 	ev.concurrent.chNumber = ev.config.chNumber
+	ev.concurrent.timeout = ev.config.timeout
 }
@@ -1,33 +1,39 @@
 package goevent
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"sync"
-)
 
-var print = fmt.Println
+	"go.uber.org/multierr"
+)
 
 type Arguments interface{}
 
 type eventFunc func(args ...Arguments)
+
+// Event function, any function may be bound.
+// When its last result is an error, it is returned by Trigger and Emit.
 type EventFunc interface{}
 
+// Called with the topic name and the error of every failed event
+type ErrorHook func(name string, err error)
+
 // Events interface who operate the events struct
 // On:bind event to the queue of events
 // Emit all the event in the queue
 type Events interface {
-	On(on string, fn func(...Arguments) error)
-	Emit()
+	On(name string, fn EventFunc, args ...Arguments) Handle
+	Emit() error
 	//Can run the special event defined based on the first Arguments
-	Trigger()
+	Trigger(names ...string) error
 }
 
 // Open parallel interface
 // GoOn:bind event to the queue and run run in gorountine
 // End:bind the last event
 type EventsConcurrent interface {
-	GoOn(fn EventFunc, args ...Arguments)
+	GoOn(fn EventFunc, args ...Arguments) error
 	End(fn EventFunc, args ...Arguments)
 }
 
@@ -41,43 +47,162 @@ type events struct {
 	curParam []Arguments
 	// Current event
 	curEvent *eventItem
+	// Temp priority for the next bound event
+	curPriority int
 	//eventsModule is running the evnets that added by devoloper
 	running bool
 	//concurrent running object
 	concurrent *concurrent
 	config     *config
+	//Last handle given to a bound event
+	lastHandle Handle
+	errorHook  ErrorHook
+	//Wrap the run of every event, the first one is the outermost
+	middlewares []Middleware
+	//Concurrent emits started by GoEmit not waited yet
+	pending []*concurrentRun
+	//Asynchronous queues of Publish
+	async *asyncDispatcher
+	//Durable log of the published events
+	store Store
 }
 
 // All the events instances
 // It is the entry
 func Classic() (this *events) {
 	this = new(events)
+	this.RWMutex = new(sync.RWMutex)
 	this.loop = make([]*eventItem, 0)
 	this.running = false
 	this.config = newConf(0, 0, false)
 	this.concurrent = NewConcurrent(this.config.chNumber)
+	this.async = newAsyncDispatcher(this)
 	return
 }
 
 // Bind event to the attribute queue of events struct
-func (this *events) On(name string, fn EventFunc, args ...Arguments) {
+// name is the topic of the event, it may contain wildcards (see Trigger).
+// The event runs every time its topic is triggered.
+// Return the handle of the event which can be used to unbind it by Off.
+func (this *events) On(name string, fn EventFunc, args ...Arguments) Handle {
+	return this.on(name, fn, false, args)
+}
+
+// Bind event that runs only the first time its topic is triggered.
+// Reset makes it run once again.
+func (this *events) Once(name string, fn EventFunc, args ...Arguments) Handle {
+	return this.on(name, fn, true, args)
+}
+
+func (this *events) on(name string, fn EventFunc, once bool, args []Arguments) Handle {
 	if fn == nil {
-		return
+		return 0
 	}
 
 	if len(name) == 0 {
 		name = "all"
 	}
 
+	this.Lock()
+	defer this.Unlock()
+
 	if len(args) == 0 {
 		args = this.curParam
 	}
 	item := NewEvent(fn, args)
+	this.lastHandle++
+	item.name = name
+	item.handle = this.lastHandle
+	item.once = once
+	item.priority = this.curPriority
 	this.curEvent = item
-	this.loop = append(this.loop, item)
+	this.loop = insertByPriority(this.loop, item)
 	this.curParam = make([]Arguments, 0)
+	this.curPriority = 0
 
-	return
+	return item.handle
+}
+
+/**
+ * Set the priority of the next bound event
+ * Events with a higher priority run first, events of the same priority
+ * run in the order they were bound. The default priority is zero.
+ */
+func (this *events) Priority(priority int) *events {
+	this.Lock()
+	defer this.Unlock()
+
+	this.curPriority = priority
+	return this
+}
+
+// Insert item after the events of a higher or equal priority
+func insertByPriority(loop []*eventItem, item *eventItem) []*eventItem {
+	i := len(loop)
+	for i > 0 && loop[i-1].priority < item.priority {
+		i--
+	}
+	loop = append(loop, nil)
+	copy(loop[i+1:], loop[i:])
+	loop[i] = item
+	return loop
+}
+
+// Rearm the once events and forget the bound params.
+func (this *events) Reset() {
+	this.Lock()
+	defer this.Unlock()
+
+	for _, e := range this.loop {
+		e.emited.Store(false)
+	}
+	this.curParam = make([]Arguments, 0)
+	this.concurrent.currentParam = make([]Arguments, 0)
+	this.running = false
+}
+
+// Remove all the serial and concurrent events to rebuild the queue.
+// The config and the error hook are kept.
+func (this *events) Clear() {
+	this.Lock()
+	defer this.Unlock()
+
+	this.loop = make([]*eventItem, 0)
+	this.curParam = make([]Arguments, 0)
+	this.curEvent = nil
+	this.running = false
+	this.concurrent.clear()
+}
+
+// Unbind all the events of the topics that named.
+// Return the number of events removed.
+func (this *events) Off(names ...string) int {
+	this.Lock()
+	defer this.Unlock()
+
+	loop := make([]*eventItem, 0, len(this.loop))
+	for _, e := range this.loop {
+		if !inNames(e.name, names) {
+			loop = append(loop, e)
+		}
+	}
+	removed := len(this.loop) - len(loop)
+	this.loop = loop
+	return removed
+}
+
+// Unbind the event of the handle returned by On.
+func (this *events) OffHandle(handle Handle) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	for i, e := range this.loop {
+		if e.handle == handle {
+			this.loop = append(this.loop[:i:i], this.loop[i+1:]...)
+			return true
+		}
+	}
+	return false
 }
 
 /**
@@ -86,15 +211,17 @@ func (this *events) On(name string, fn EventFunc, args ...Arguments) {
  * It would be clear the variable curParam when invoke On function.
  */
 func (this *events) Bind(args ...Arguments) *events {
+	this.Lock()
+	defer this.Unlock()
+
 	this.curParam = args
 	this.concurrent.currentParam = args
 
-	last, ok := getSlicePop(this.loop)
-	if len(last.param) == 0 && ok == nil {
+	if last := this.curEvent; last != nil && len(last.param) == 0 {
 		last.param = args
 	}
 
-	last, _ = getSlicePop(this.concurrent.loop)
+	last, ok := getSlicePop(this.concurrent.loop)
 	if len(last.param) == 0 && ok == nil {
 		last.param = args
 	}
@@ -105,30 +232,151 @@ func (this *events) Bind(args ...Arguments) *events {
 /**
  * Tiigger events
  * If the lenght of names bigger than one It will trigger the group events that named.
+ * Names are dot separated topics, "*" matches one level and "**" any number of levels,
+ * so Trigger("user.*") runs the events of "user.created" and On("user.**") is triggered
+ * by "user.created.admin".
+ * Every matched event runs even if a previous one failed or panicked,
+ * their errors are returned combined.
+ * An event returning ErrStopPropagation stops the following events of its topic.
  */
-func (this *events) Trigger(names ...string) {
+func (this *events) Trigger(names ...string) error {
+	return this.trigger(names, nil)
+}
+
+// Trigger the events of names, args replace the bound params when not empty.
+func (this *events) trigger(names []string, args []Arguments) error {
+	run := this.matched(names, args)
+
+	var errs error
+	stopped := make(map[string]bool)
+	for i, e := range run.loop {
+		if stopped[e.name] || !e.arm() {
+			continue
+		}
+		param := run.params[i]
+		err := runMiddlewares(run.middlewares, e.name, func() error {
+			return e.call(param...)
+		})
+		if errors.Is(err, ErrStopPropagation) {
+			stopped[e.name] = true
+			continue
+		}
+		if err != nil {
+			if run.errorHook != nil {
+				run.errorHook(e.name, err)
+			}
+			errs = multierr.Append(errs, err)
+		}
+	}
+	return errs
+}
 
-	loop := this.loop
+// The events matched by a trigger with their arguments
+type triggerRun struct {
+	loop        []*eventItem
+	params      [][]Arguments
+	errorHook   ErrorHook
+	middlewares []Middleware
+}
+
+// Return the events matching names with their arguments.
+// The events run outside of the lock so they can bind other events.
+func (this *events) matched(names []string, args []Arguments) *triggerRun {
+	this.Lock()
+	defer this.Unlock()
 
 	this.running = true
-	for _, e := range loop {
-		print(e.param)
-		param := e.param
+	run := &triggerRun{errorHook: this.errorHook, middlewares: this.middlewares}
+	for _, e := range this.loop {
+		if len(names) > 0 && !matchAny(e.name, names) {
+			continue
+		}
+		param := args
+		if len(param) == 0 {
+			param = e.param
+		}
 		if len(param) == 0 {
 			param = this.curParam
 		}
-		e.exec(param...)
+		run.loop = append(run.loop, e)
+		run.params = append(run.params, param)
 	}
+	return run
+}
+
+// Add middlewares wrapping the run of every serial and concurrent event.
+func (this *events) Use(middlewares ...Middleware) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.middlewares = append(this.middlewares[:len(this.middlewares):len(this.middlewares)], middlewares...)
+	this.concurrent.middlewares = this.middlewares
 }
 
 // Trigger all the events
-func (this *events) Emit() {
-	this.Trigger()
-	this.concurrent.emit()
+func (this *events) Emit() error {
+	return this.EmitContext(context.Background())
+}
+
+// Trigger all the events, the concurrent events not started yet are skipped once ctx is done.
+func (this *events) EmitContext(ctx context.Context) error {
+	err := this.Trigger()
+
+	run := this.startConcurrent(ctx, false)
+	if !run.waited {
+		return err
+	}
+	return multierr.Append(err, run.wait())
+}
+
+// Start the concurrent events without waiting for them, Wait returns their errors.
+func (this *events) GoEmit(ctx context.Context) {
+	this.startConcurrent(ctx, true)
+}
+
+// Start a snapshot of the concurrent events.
+// It is kept for Wait when detached or when the events are not waited by Emit.
+func (this *events) startConcurrent(ctx context.Context, detached bool) *concurrentRun {
+	this.Lock()
+	run := this.concurrent.snapshot()
+	if detached || !run.waited {
+		this.pending = append(this.pending, run)
+	}
+	this.Unlock()
+
+	run.start(ctx)
+	return run
+}
+
+// Wait the concurrent events started by GoEmit.
+// Return the errors of all the finished events.
+func (this *events) Wait() error {
+	this.Lock()
+	pending := this.pending
+	this.pending = nil
+	this.Unlock()
+
+	var err error
+	for _, run := range pending {
+		err = multierr.Append(err, run.wait())
+	}
+	return err
+}
+
+// Set the hook called with the error of every failed event
+func (this *events) OnError(hook ErrorHook) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.errorHook = hook
+	this.concurrent.errorHook = hook
 }
 
 // Add concurrent event
 func (this *events) GoOn(fn EventFunc, args ...Arguments) error {
+	this.Lock()
+	defer this.Unlock()
+
 	this.curParam = args
 	this.concurrent.on(fn, args...)
 	return nil
@@ -136,6 +384,9 @@ func (this *events) GoOn(fn EventFunc, args ...Arguments) error {
 
 // Add the last event
 func (this *events) End(fn EventFunc, args ...Arguments) {
+	this.Lock()
+	defer this.Unlock()
+
 	this.concurrent.end(fn, args...)
 }
 
@@ -0,0 +1,210 @@
+package goevent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
+)
+
+func TestEventErrors(t *testing.T) {
+	ev := Classic()
+	var hooked []string
+	ev.OnError(func(name string, err error) {
+		hooked = append(hooked, name)
+	})
+
+	var ran []string
+	ev.On("failing", func() error {
+		ran = append(ran, "failing")
+		return errors.New("failed")
+	})
+	ev.On("panicking", func() {
+		ran = append(ran, "panicking")
+		panic("boom")
+	})
+	ev.Bind("only one").On("arity", func(a, b string) {
+		ran = append(ran, "arity")
+	})
+	ev.Bind(42).On("mismatch", func(s string) {
+		ran = append(ran, "mismatch")
+	})
+	ev.On("ok", func(args ...Arguments) error {
+		ran = append(ran, "ok")
+		return nil
+	})
+
+	err := ev.Trigger()
+	require.Equal(t, []string{"failing", "panicking", "ok"}, ran)
+	require.Len(t, multierr.Errors(err), 4)
+	require.Contains(t, err.Error(), "event panicking panic: boom")
+	require.Contains(t, err.Error(), "event arity expects 2 arguments, got 1")
+	require.Equal(t, []string{"failing", "panicking", "arity", "mismatch"}, hooked)
+}
+
+func TestConcurrentErrors(t *testing.T) {
+	ev := Classic()
+	ev.GoOn(func() { panic("boom") })
+	ev.GoOn(func() error { return errors.New("failed") })
+	ev.GoOn(func() {})
+	var ended bool
+	ev.End(func() { ended = true })
+
+	err := ev.Emit()
+	require.Len(t, multierr.Errors(err), 2)
+	require.True(t, ended)
+}
+
+// Run with -race: binding and emitting from many goroutines must not race.
+func TestEventsConcurrentSafety(t *testing.T) {
+	ev := Classic()
+	var count int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				name := fmt.Sprintf("topic.%d", j%4)
+				ev.Bind(i, j)
+				ev.On(name, func(i, j int) { atomic.AddInt64(&count, 1) }, i, j)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_ = ev.GoOn(func(args ...Arguments) { atomic.AddInt64(&count, 1) }, j)
+				_ = ev.Trigger("topic.*")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_ = ev.Emit()
+				ev.Off("topic.0")
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, ev.Emit())
+	require.Greater(t, atomic.LoadInt64(&count), int64(0))
+}
+
+func TestConcurrentPool(t *testing.T) {
+	ev := Classic()
+	ev.Conf(2, 0)
+	ev.SetTimeout(50 * time.Millisecond)
+
+	var running, maxRunning int64
+	for i := 0; i < 6; i++ {
+		_ = ev.GoOn(func() {
+			n := atomic.AddInt64(&running, 1)
+			for {
+				max := atomic.LoadInt64(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt64(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt64(&running, -1)
+		})
+	}
+	var cancelled int64
+	_ = ev.GoOn(func(ctx context.Context) {
+		<-ctx.Done()
+		atomic.AddInt64(&cancelled, 1)
+	})
+
+	ev.GoEmit(context.Background())
+	err := ev.Wait()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, multierr.Errors(err), 1)
+	require.Equal(t, int64(2), atomic.LoadInt64(&maxRunning))
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&cancelled) == 1 }, time.Second, time.Millisecond)
+	require.NoError(t, ev.Wait())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, ev.EmitContext(ctx), context.Canceled)
+
+	// events ignoring the timeout keep their worker busy
+	ev = Classic()
+	ev.Conf(2, 0)
+	ev.SetTimeout(5 * time.Millisecond)
+	running, maxRunning = 0, 0
+	for i := 0; i < 6; i++ {
+		_ = ev.GoOn(func() {
+			n := atomic.AddInt64(&running, 1)
+			for {
+				max := atomic.LoadInt64(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt64(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt64(&running, -1)
+		})
+	}
+	err = ev.Emit()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, multierr.Errors(err), 6)
+	require.Equal(t, int64(2), atomic.LoadInt64(&maxRunning))
+	require.Equal(t, int64(0), atomic.LoadInt64(&running))
+}
+
+func TestOnceAndReset(t *testing.T) {
+	ev := Classic()
+	var on, once int
+	ev.On("tick", func() { on++ })
+	ev.Once("tick", func() { once++ })
+
+	require.NoError(t, ev.Trigger("tick"))
+	require.NoError(t, ev.Trigger("tick"))
+	require.Equal(t, 2, on)
+	require.Equal(t, 1, once)
+
+	ev.Reset()
+	require.NoError(t, ev.Trigger("tick"))
+	require.Equal(t, 3, on)
+	require.Equal(t, 2, once)
+
+	var gone bool
+	_ = ev.GoOn(func() { gone = true })
+	ev.Clear()
+	require.NoError(t, ev.Emit())
+	require.Equal(t, 3, on)
+	require.False(t, gone)
+}
+
+func TestPriorityAndMiddleware(t *testing.T) {
+	ev := Classic()
+	var trace []string
+	ev.Use(func(name string, next func() error) error {
+		trace = append(trace, "before "+name)
+		err := next()
+		trace = append(trace, "after "+name)
+		return err
+	})
+
+	ev.On("a", func() { trace = append(trace, "a default") })
+	ev.Priority(10).On("a", func() { trace = append(trace, "a high") })
+	ev.Priority(-1).On("a", func() { trace = append(trace, "a low") })
+	ev.Priority(5).On("a", func() error {
+		trace = append(trace, "a stop")
+		return ErrStopPropagation
+	})
+	ev.On("b", func() { trace = append(trace, "b") })
+
+	require.NoError(t, ev.Trigger("a", "b"))
+	require.Equal(t, []string{
+		"before a", "a high", "after a",
+		"before a", "a stop", "after a",
+		"before b", "b", "after b",
+	}, trace)
+}
@@ -1,52 +1,163 @@
 package goevent
 
-import "reflect"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
 
 // Event interface
 type EventItem interface {
-	exec(args ...Arguments)
+	exec(args ...Arguments) error
 }
 
 type Event interface {
 }
 
+// Handle identify a bound event, it is used to unbind it.
+type Handle uint64
+
 // The struct of event
 type eventItem struct {
 	fn    EventFunc
 	param []Arguments
 	//Whether the event has run
-	emited bool
-	len    int
+	emited atomic.Bool
+	//Run the event a single time until it is reset
+	once bool
+	//Events with a higher priority run first
+	priority int
+	len      int
+	//Topic name the event was bound to
+	name   string
+	handle Handle
 }
 
 // Create a new event
 func NewEvent(fn EventFunc, param []Arguments) *eventItem {
 	l := reflect.TypeOf(fn).NumIn()
-	return &eventItem{fn, param, false, l}
+	return &eventItem{fn: fn, param: param, len: l}
 }
 
 // Excute the current event
-func (this *eventItem) exec(args ...Arguments) {
-	if this.emited {
-		return
+// A once event only runs the first time, until it is reset.
+// Return the error of the event function, a panic is recovered as an error.
+func (this *eventItem) exec(args ...Arguments) error {
+	if !this.arm() {
+		return nil
+	}
+	return this.call(args...)
+}
+
+// Mark the event as run, return false if it is a once event that already ran
+func (this *eventItem) arm() bool {
+	return this.emited.CompareAndSwap(false, true) || !this.once
+}
+
+// Call the event function with args whether it has run or not
+func (this *eventItem) call(args ...Arguments) error {
+	_, err := this.invoke(args...)
+	return err
+}
+
+// Call the event function and return its first result when it is not the error
+func (this *eventItem) invoke(args ...Arguments) (result Arguments, err error) {
+	argvs, err := this.getArgs(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("event %s panic: %v", this.name, r)
+		}
+	}()
+	out := reflect.ValueOf(this.fn).Call(argvs)
+	if err = resultError(out); err != nil {
+		return nil, err
 	}
-	argvs := getArgs(args...)
-	if len(argvs) > this.len {
-		argvs = argvs[:this.len]
+	if len(out) > 1 || (len(out) == 1 && !out[0].Type().Implements(errorType)) {
+		result = out[0].Interface()
 	}
-	reflect.ValueOf(this.fn).Call(argvs)
-	this.emited = true
+	return result, nil
+}
+
+// Call the event function, ctx is given as first argument when the function expects a context.
+func (this *eventItem) callContext(ctx context.Context, args ...Arguments) error {
+	return this.call(this.withContext(ctx, args)...)
 }
 
-// Convert  type of ...Arguments to reflect.Value slice type
-func getArgs(args ...Arguments) []reflect.Value {
+// Prepend ctx to args when the function expects a context.
+func (this *eventItem) withContext(ctx context.Context, args []Arguments) []Arguments {
+	fnType := reflect.TypeOf(this.fn)
+	if fnType.NumIn() > 0 && fnType.In(0) == contextType {
+		args = append([]Arguments{ctx}, args...)
+	}
+	return args
+}
+
+// Convert type of ...Arguments to the reflect.Value of the function parameters
+// Extra arguments are dropped, missing or mismatched ones return an error.
+func (this *eventItem) getArgs(args ...Arguments) ([]reflect.Value, error) {
+	fnType := reflect.TypeOf(this.fn)
+	fixed := this.len
+	if fnType.IsVariadic() {
+		fixed--
+	} else if len(args) > this.len {
+		args = args[:this.len]
+	}
+	if len(args) < fixed {
+		return nil, fmt.Errorf("event %s expects %d arguments, got %d", this.name, fixed, len(args))
+	}
+
 	var ma = make([]reflect.Value, len(args))
 	for k, v := range args {
+		var in reflect.Type
+		if k < fixed {
+			in = fnType.In(k)
+		} else {
+			in = fnType.In(fixed).Elem()
+		}
+		if v == nil {
+			ma[k] = reflect.Zero(in)
+			continue
+		}
+		if raw, ok := v.(jsonArg); ok {
+			//Replayed argument, decode it to the parameter type
+			value := reflect.New(in)
+			if err := json.Unmarshal(raw, value.Interface()); err != nil {
+				return nil, fmt.Errorf("event %s argument %d: %w", this.name, k, err)
+			}
+			ma[k] = value.Elem()
+			continue
+		}
 		ma[k] = reflect.ValueOf(v)
+		if !ma[k].Type().AssignableTo(in) {
+			return nil, fmt.Errorf("event %s argument %d: %s is not assignable to %s", this.name, k, ma[k].Type(), in)
+		}
 	}
-	return ma
+	return ma, nil
 }
 
+// Return the last result of the event function when it is a non nil error
+func resultError(out []reflect.Value) error {
+	if len(out) == 0 {
+		return nil
+	}
+	last := out[len(out)-1]
+	if last.Kind() != reflect.Interface || !last.Type().Implements(errorType) || last.IsNil() {
+		return nil
+	}
+	return last.Interface().(error)
+}
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
 // Init function
 func initFn() {
 }
@@ -0,0 +1,34 @@
+package goevent
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned by an event to stop the following events of the same topic.
+// It is not reported as an error by Trigger.
+var ErrStopPropagation = errors.New("goevent: stop propagation")
+
+// Middleware wraps the run of an event, for logging, timing or tracing.
+// next runs the following middlewares and the event, its error should be returned.
+type Middleware func(name string, next func() error) error
+
+// Run fn wrapped by the middlewares, a panic in a middleware is recovered as an error.
+func runMiddlewares(middlewares []Middleware, name string, fn func() error) (err error) {
+	if len(middlewares) == 0 {
+		return fn()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("event %s middleware panic: %v", name, r)
+		}
+	}()
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		middleware, next := middlewares[i], fn
+		fn = func() error {
+			return middleware(name, next)
+		}
+	}
+	return fn()
+}
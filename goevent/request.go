@@ -0,0 +1,118 @@
+package goevent
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"go.uber.org/multierr"
+)
+
+// Returned by RequestFirst when no event answered
+var ErrNoResponse = errors.New("goevent: no response")
+
+// A response of an event to a request
+type response struct {
+	index  int
+	result Arguments
+	err    error
+	//A once event that already ran
+	skipped bool
+}
+
+/**
+ * Request runs the events of the topic concurrently with args and collects
+ * their results, in the order the events would be triggered.
+ * An event answers with its first result, e.g. func(url string) (string, error).
+ * Events whose first parameter is a context.Context receive ctx.
+ * When ctx is done the results received so far are returned with its error.
+ */
+func (this *events) Request(ctx context.Context, name string, args ...Arguments) ([]Arguments, error) {
+	run := this.matched([]string{name}, args)
+	responses := this.request(ctx, run)
+
+	results := make([]Arguments, len(run.loop))
+	answered := make([]bool, len(run.loop))
+	var errs error
+	for range run.loop {
+		select {
+		case <-ctx.Done():
+			return collect(results, answered), multierr.Append(errs, ctx.Err())
+		case res := <-responses:
+			if res.err != nil {
+				errs = multierr.Append(errs, res.err)
+				continue
+			}
+			if !res.skipped {
+				results[res.index], answered[res.index] = res.result, true
+			}
+		}
+	}
+	return collect(results, answered), errs
+}
+
+/**
+ * RequestFirst runs the events of the topic concurrently with args and returns
+ * the first non empty result, for plugin style hooks like "who can handle this URL?".
+ * An event declines by returning a nil or zero result.
+ */
+func (this *events) RequestFirst(ctx context.Context, name string, args ...Arguments) (Arguments, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	run := this.matched([]string{name}, args)
+	responses := this.request(ctx, run)
+
+	var errs error
+	for range run.loop {
+		select {
+		case <-ctx.Done():
+			return nil, multierr.Append(errs, ctx.Err())
+		case res := <-responses:
+			if res.err != nil {
+				errs = multierr.Append(errs, res.err)
+				continue
+			}
+			if res.result != nil && !reflect.ValueOf(res.result).IsZero() {
+				return res.result, nil
+			}
+		}
+	}
+	return nil, multierr.Append(errs, ErrNoResponse)
+}
+
+// Run the events of the trigger concurrently and send their responses.
+// The channel is buffered so late events never block.
+func (this *events) request(ctx context.Context, run *triggerRun) <-chan response {
+	responses := make(chan response, len(run.loop))
+	for i, e := range run.loop {
+		if !e.arm() {
+			responses <- response{index: i, skipped: true}
+			continue
+		}
+		go func(i int, e *eventItem, param []Arguments) {
+			var result Arguments
+			err := runMiddlewares(run.middlewares, e.name, func() error {
+				var err error
+				result, err = e.invoke(e.withContext(ctx, param)...)
+				return err
+			})
+			if err != nil && run.errorHook != nil {
+				run.errorHook(e.name, err)
+			}
+			responses <- response{index: i, result: result, err: err}
+		}(i, e, run.params[i])
+	}
+	return responses
+}
+
+// Return the results of the events that answered
+func collect(results []Arguments, answered []bool) []Arguments {
+	collected := make([]Arguments, 0, len(results))
+	for i, result := range results {
+		if answered[i] {
+			collected = append(collected, result)
+		}
+	}
+	return collected
+}
@@ -0,0 +1,59 @@
+package goevent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequest(t *testing.T) {
+	ev := Classic()
+	ev.On("handler", func(url string) string {
+		if strings.HasPrefix(url, "http") {
+			return "http"
+		}
+		return ""
+	})
+	ev.On("handler", func(url string) (string, error) {
+		time.Sleep(10 * time.Millisecond)
+		if strings.HasPrefix(url, "ftp") {
+			return "ftp", nil
+		}
+		return "", nil
+	})
+	ev.On("handler", func(url string) (string, error) {
+		return "", errors.New("plugin crashed")
+	})
+
+	results, err := ev.Request(context.Background(), "handler", "ftp://example.com")
+	require.EqualError(t, err, "plugin crashed")
+	require.Equal(t, []Arguments{"", "ftp"}, results)
+
+	first, err := ev.RequestFirst(context.Background(), "handler", "ftp://example.com")
+	require.NoError(t, err)
+	require.Equal(t, "ftp", first)
+
+	first, err = ev.RequestFirst(context.Background(), "handler", "gopher://example.com")
+	require.ErrorIs(t, err, ErrNoResponse)
+	require.Nil(t, first)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	ev := Classic()
+	ev.On("slow", func(ctx context.Context) int {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		return 0
+	})
+	ev.On("slow", func() int { return 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	results, err := ev.Request(ctx, "slow")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, []Arguments{1}, results)
+}
@@ -0,0 +1,285 @@
+package goevent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// A persisted event
+type Record struct {
+	ID    uint64            `json:"id"`
+	Topic string            `json:"topic"`
+	Args  []json.RawMessage `json:"args,omitempty"`
+	Time  time.Time         `json:"time"`
+}
+
+// Store persists the published events until they are acknowledged
+type Store interface {
+	// Append the record, setting its ID
+	Append(record *Record) error
+	// Ack the record of id, it will not be replayed
+	Ack(id uint64) error
+	// Return the records not acknowledged, in append order
+	Pending() ([]Record, error)
+	Close() error
+}
+
+// Argument decoded from a record, it is converted to the event parameter type
+type jsonArg json.RawMessage
+
+// Persist the published events to the store
+// Publish and TriggerDurable record the events before running them and
+// acknowledge them once all their events succeeded, Replay runs the others.
+func (this *events) Persist(store Store) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.store = store
+}
+
+// Record the event in the store, return zero when there is no store
+func (this *events) persist(name string, args []Arguments) (uint64, error) {
+	this.RLock()
+	store := this.store
+	this.RUnlock()
+	if store == nil {
+		return 0, nil
+	}
+
+	record := &Record{Topic: name, Time: time.Now()}
+	for k, arg := range args {
+		raw, err := json.Marshal(arg)
+		if err != nil {
+			return 0, fmt.Errorf("event %s argument %d: %w", name, k, err)
+		}
+		record.Args = append(record.Args, raw)
+	}
+	if err := store.Append(record); err != nil {
+		return 0, err
+	}
+	return record.ID, nil
+}
+
+// Acknowledge the record when its events succeeded
+func (this *events) ack(id uint64, err error) {
+	this.RLock()
+	store := this.store
+	hook := this.errorHook
+	this.RUnlock()
+	if err != nil || store == nil {
+		return
+	}
+	if err := store.Ack(id); err != nil && hook != nil {
+		hook("store", err)
+	}
+}
+
+// Record the event then trigger the topic with args synchronously.
+func (this *events) TriggerDurable(name string, args ...Arguments) error {
+	id, err := this.persist(name, args)
+	if err != nil {
+		return err
+	}
+	err = this.trigger([]string{name}, args)
+	if id != 0 {
+		this.ack(id, err)
+	}
+	return err
+}
+
+// Run the events of the records not acknowledged, after a crash for example.
+// The arguments are decoded from JSON into the parameter types of the events.
+func (this *events) Replay() error {
+	this.RLock()
+	store := this.store
+	this.RUnlock()
+	if store == nil {
+		return nil
+	}
+
+	records, err := store.Pending()
+	if err != nil {
+		return err
+	}
+	var errs error
+	for _, record := range records {
+		args := make([]Arguments, len(record.Args))
+		for k, raw := range record.Args {
+			args[k] = jsonArg(raw)
+		}
+		err := this.trigger([]string{record.Topic}, args)
+		this.ack(record.ID, err)
+		errs = multierr.Append(errs, err)
+	}
+	return errs
+}
+
+// A line of the file store, either a record or the ack of a record
+type fileEntry struct {
+	Record *Record `json:"record,omitempty"`
+	Ack    uint64  `json:"ack,omitempty"`
+}
+
+// Store appending the records and acks to a JSON lines file.
+// The file is compacted when opened and closed, only the pending records are kept.
+type FileStore struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	lastID  uint64
+	pending map[uint64]Record
+}
+
+// Open the file store at path, the records of a previous run are loaded.
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	store := &FileStore{path: path, pending: make(map[uint64]Record)}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry fileEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			//A line truncated by a crash
+			continue
+		}
+		if entry.Record != nil {
+			store.pending[entry.Record.ID] = *entry.Record
+			if entry.Record.ID > store.lastID {
+				store.lastID = entry.Record.ID
+			}
+		} else {
+			delete(store.pending, entry.Ack)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := store.compact(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (this *FileStore) Append(record *Record) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	this.lastID++
+	record.ID = this.lastID
+	if err := this.write(fileEntry{Record: record}); err != nil {
+		return err
+	}
+	this.pending[record.ID] = *record
+	return nil
+}
+
+func (this *FileStore) Ack(id uint64) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if _, ok := this.pending[id]; !ok {
+		return nil
+	}
+	if err := this.write(fileEntry{Ack: id}); err != nil {
+		return err
+	}
+	delete(this.pending, id)
+	return nil
+}
+
+func (this *FileStore) Pending() ([]Record, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	return this.records(), nil
+}
+
+// The pending records in the order they were appended
+func (this *FileStore) records() []Record {
+	records := make([]Record, 0, len(this.pending))
+	for _, record := range this.pending {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+	return records
+}
+
+func (this *FileStore) Close() error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	err := this.compact()
+	return multierr.Append(err, this.file.Close())
+}
+
+// Rewrite the file with only the pending records, dropping the acked ones,
+// their acks and the lines truncated by a crash.
+// The new file replaces the old one atomically and is kept open to append.
+func (this *FileStore) compact() error {
+	tmp := this.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	for _, record := range this.records() {
+		record := record
+		line, err := json.Marshal(fileEntry{Record: &record})
+		if err == nil {
+			_, err = writer.Write(append(line, '\n'))
+		}
+		if err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := os.Rename(tmp, this.path); err != nil {
+		file.Close()
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(this.path)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+
+	if this.file != nil {
+		this.file.Close()
+	}
+	this.file = file
+	return nil
+}
+
+// Write a line and sync it to disk so it survives a crash
+func (this *FileStore) write(entry fileEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := this.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return this.file.Sync()
+}
@@ -0,0 +1,82 @@
+package goevent
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type scanJob struct {
+	Host  string `json:"host"`
+	Ports []int  `json:"ports"`
+}
+
+func TestFileStoreReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+
+	ev := Classic()
+	ev.Persist(store)
+	ev.On("scan", func(job scanJob, attempt int) error {
+		if job.Host == "down.example.com" {
+			return errors.New("unreachable")
+		}
+		return nil
+	})
+	require.NoError(t, ev.TriggerDurable("scan", scanJob{"up.example.com", []int{80}}, 1))
+	require.Error(t, ev.TriggerDurable("scan", scanJob{"down.example.com", []int{443}}, 1))
+	require.NoError(t, ev.Publish("scan", scanJob{"async.example.com", nil}, 1))
+	require.NoError(t, ev.Close())
+	require.NoError(t, store.Close())
+
+	// simulate a crash in the middle of a write
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"record":{"id":9,"top`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	store, err = NewFileStore(path)
+	require.NoError(t, err)
+	pending, err := store.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, "scan", pending[0].Topic)
+	// the acked records, their acks and the truncated line are compacted
+	require.Equal(t, 1, countLines(t, path))
+
+	var replayed []scanJob
+	ev = Classic()
+	ev.Persist(store)
+	ev.On("scan", func(job scanJob, attempt int) {
+		require.Equal(t, 1, attempt)
+		replayed = append(replayed, job)
+	})
+	require.NoError(t, ev.Replay())
+	require.Equal(t, []scanJob{{"down.example.com", []int{443}}}, replayed)
+
+	pending, err = store.Pending()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+	require.NoError(t, ev.TriggerDurable("scan", scanJob{"next.example.com", nil}, 1))
+	require.NoError(t, store.Close())
+	require.Equal(t, 0, countLines(t, path))
+
+	store, err = NewFileStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	pending, err = store.Pending()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
+
+func countLines(t *testing.T, path string) int {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return bytes.Count(data, []byte("\n"))
+}
@@ -0,0 +1,64 @@
+package goevent
+
+import "strings"
+
+// Topic separator and wildcards
+const (
+	topicSep      = "."
+	topicWildcard = "*"
+	topicDeep     = "**"
+)
+
+// Whether the event topic is triggered by one of the names.
+// Wildcards are allowed on both sides.
+func matchAny(topic string, names []string) bool {
+	for _, name := range names {
+		if matchTopic(name, topic) || matchTopic(topic, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the topic is exactly in names
+func inNames(topic string, names []string) bool {
+	for _, name := range names {
+		if name == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// Match the topic with the pattern.
+// "*" matches exactly one level, "**" matches zero or more levels.
+func matchTopic(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+	return matchLevels(strings.Split(pattern, topicSep), strings.Split(topic, topicSep))
+}
+
+func matchLevels(pattern, topic []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case topicDeep:
+			for i := 0; i <= len(topic); i++ {
+				if matchLevels(pattern[1:], topic[i:]) {
+					return true
+				}
+			}
+			return false
+		case topicWildcard:
+			if len(topic) == 0 {
+				return false
+			}
+		default:
+			if len(topic) == 0 || pattern[0] != topic[0] {
+				return false
+			}
+		}
+		pattern, topic = pattern[1:], topic[1:]
+	}
+	return len(topic) == 0
+}
@@ -0,0 +1,51 @@
+package goevent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		match          bool
+	}{
+		{"user", "user", true},
+		{"user.*", "user.created", true},
+		{"user.*", "user", false},
+		{"user.*", "user.created.admin", false},
+		{"user.**", "user", true},
+		{"user.**", "user.created.admin", true},
+		{"*.created", "user.created", true},
+		{"**.created", "org.user.created", true},
+		{"user.created", "user.deleted", false},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.match, matchTopic(test.pattern, test.topic), "%s %s", test.pattern, test.topic)
+	}
+}
+
+func TestTriggerTopics(t *testing.T) {
+	var called []string
+	ev := Classic()
+	record := func(name string) func() {
+		return func() { called = append(called, name) }
+	}
+	ev.On("user.created", record("created"))
+	ev.On("user.deleted", record("deleted"))
+	handle := ev.On("user.*", record("any user"))
+	ev.On("order.paid", record("paid"))
+
+	ev.Trigger("user.created")
+	require.Equal(t, []string{"created", "any user"}, called)
+
+	called = nil
+	ev.Trigger("order.*")
+	require.Equal(t, []string{"paid"}, called)
+
+	require.True(t, ev.OffHandle(handle))
+	require.False(t, ev.OffHandle(handle))
+	require.Equal(t, 1, ev.Off("user.deleted"))
+	require.Len(t, ev.loop, 2)
+}
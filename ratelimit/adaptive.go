@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// AdaptiveOptions configures the rate adjustments of an Adaptive limiter
+type AdaptiveOptions struct {
+	// MinRate and MaxRate bound the rate in tokens per second.
+	// MinRate is 1 by default, or MaxRate when it is lower, MaxRate 0 does not bound it.
+	MinRate float64
+	MaxRate float64
+	// Increase is added to the rate on every success, 1 by default
+	Increase float64
+	// Decrease multiplies the rate on failure, 0.5 by default
+	Decrease float64
+	// Cooldown ignores the failures following a decrease for this duration,
+	// so the requests in flight when a target started failing cut the rate only once
+	Cooldown time.Duration
+}
+
+// Adaptive throttles a token bucket with the outcome of the requests (AIMD):
+// the rate grows additively while they succeed and is cut multiplicatively when they fail,
+// e.g. on a 429 status or a timeout.
+type Adaptive struct {
+	*TokenBucket
+
+	mu           sync.Mutex
+	options      AdaptiveOptions
+	lastDecrease time.Time
+}
+
+// NewAdaptive controls the rate of bucket, its current rate is clamped to the options bounds
+func NewAdaptive(bucket *TokenBucket, options AdaptiveOptions) *Adaptive {
+	if options.MaxRate < 0 {
+		options.MaxRate = 0
+	}
+	if options.MinRate <= 0 {
+		options.MinRate = 1
+		if options.MaxRate > 0 && options.MaxRate < options.MinRate {
+			options.MinRate = options.MaxRate
+		}
+	}
+	if options.MaxRate > 0 && options.MaxRate < options.MinRate {
+		options.MaxRate = options.MinRate
+	}
+	if options.Increase <= 0 {
+		options.Increase = 1
+	}
+	if options.Decrease <= 0 || options.Decrease >= 1 {
+		options.Decrease = 0.5
+	}
+	adaptive := &Adaptive{TokenBucket: bucket, options: options}
+	bucket.SetRate(adaptive.clamp(bucket.Rate()))
+	return adaptive
+}
+
+func (adaptive *Adaptive) clamp(rate float64) float64 {
+	if rate < adaptive.options.MinRate {
+		return adaptive.options.MinRate
+	}
+	if adaptive.options.MaxRate > 0 && rate > adaptive.options.MaxRate {
+		return adaptive.options.MaxRate
+	}
+	return rate
+}
+
+// Success increases the rate additively
+func (adaptive *Adaptive) Success() {
+	adaptive.mu.Lock()
+	defer adaptive.mu.Unlock()
+
+	adaptive.SetRate(adaptive.clamp(adaptive.Rate() + adaptive.options.Increase))
+}
+
+// Failure decreases the rate multiplicatively, unless it was already decreased during the cooldown
+func (adaptive *Adaptive) Failure() {
+	adaptive.mu.Lock()
+	defer adaptive.mu.Unlock()
+
+	now := adaptive.clock.Now()
+	if !adaptive.lastDecrease.IsZero() && now.Sub(adaptive.lastDecrease) < adaptive.options.Cooldown {
+		return
+	}
+	adaptive.lastDecrease = now
+	adaptive.SetRate(adaptive.clamp(adaptive.Rate() * adaptive.options.Decrease))
+}
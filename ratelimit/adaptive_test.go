@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptive(t *testing.T) {
+	t.Run("Clamped initial rate", func(t *testing.T) {
+		bucket := NewTokenBucketWithClock(context.Background(), 100, 1, newFakeClock())
+		adaptive := NewAdaptive(bucket, AdaptiveOptions{MinRate: 1, MaxRate: 10})
+		require.Equal(t, 10.0, adaptive.Rate())
+	})
+
+	t.Run("Additive increase multiplicative decrease", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewTokenBucketWithClock(context.Background(), 8, 1, clock)
+		adaptive := NewAdaptive(bucket, AdaptiveOptions{MinRate: 1, MaxRate: 10})
+
+		adaptive.Failure()
+		require.Equal(t, 4.0, adaptive.Rate())
+		adaptive.Failure()
+		require.Equal(t, 2.0, adaptive.Rate())
+		adaptive.Failure()
+		adaptive.Failure()
+		require.Equal(t, 1.0, adaptive.Rate(), "rate below the minimum")
+
+		adaptive.Success()
+		require.Equal(t, 2.0, adaptive.Rate())
+		for i := 0; i < 20; i++ {
+			adaptive.Success()
+		}
+		require.Equal(t, 10.0, adaptive.Rate(), "rate above the maximum")
+	})
+
+	t.Run("Cooldown", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewTokenBucketWithClock(context.Background(), 8, 1, clock)
+		adaptive := NewAdaptive(bucket, AdaptiveOptions{MinRate: 1, MaxRate: 8, Decrease: 0.25, Cooldown: time.Second})
+
+		adaptive.Failure()
+		adaptive.Failure()
+		require.Equal(t, 2.0, adaptive.Rate())
+		clock.Advance(time.Second)
+		adaptive.Failure()
+		require.Equal(t, 1.0, adaptive.Rate())
+	})
+
+	t.Run("Throttles takes", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewTokenBucketWithClock(context.Background(), 10, 1, clock)
+		adaptive := NewAdaptive(bucket, AdaptiveOptions{MinRate: 1, MaxRate: 10, Decrease: 0.1})
+		adaptive.Take()
+		adaptive.Failure()
+
+		done := takeAsync(adaptive.Take)
+		requireBlocked(t, clock, done)
+		clock.Advance(100 * time.Millisecond)
+		requireBlocked(t, clock, done)
+		clock.Advance(900 * time.Millisecond)
+		requireDone(t, done)
+	})
+
+	t.Run("Default minimum rate", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewTokenBucketWithClock(context.Background(), 10, 1, clock)
+		adaptive := NewAdaptive(bucket, AdaptiveOptions{MaxRate: 10})
+		for i := 0; i < 60; i++ {
+			adaptive.Failure()
+		}
+		require.Equal(t, 1.0, adaptive.Rate())
+
+		adaptive.Take()
+		done := takeAsync(adaptive.Take)
+		requireBlocked(t, clock, done)
+		clock.Advance(999 * time.Millisecond)
+		requireBlocked(t, clock, done)
+		clock.Advance(time.Millisecond)
+		requireDone(t, done)
+
+		adaptive.Success()
+		require.Equal(t, 2.0, adaptive.Rate())
+	})
+
+	t.Run("Maximum rate below one", func(t *testing.T) {
+		bucket := NewTokenBucketWithClock(context.Background(), 10, 1, newFakeClock())
+		adaptive := NewAdaptive(bucket, AdaptiveOptions{MaxRate: 0.5})
+		require.Equal(t, 0.5, adaptive.Rate())
+		adaptive.Failure()
+		require.Equal(t, 0.5, adaptive.Rate())
+	})
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the time source of the limiters, tests replace it by a fake one
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse then sends the current time, like time.After
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// maxWait bounds the waits computed from a rate, which would overflow
+// a time.Duration for a rate close to zero
+const maxWait = time.Duration(1 << 62)
+
+// seconds converts seconds to a duration, up to maxWait
+func seconds(s float64) time.Duration {
+	if s >= maxWait.Seconds() {
+		return maxWait
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+// sleep waits d on clock, giving up when ctx is done or the limiter is stopped
+func sleep(ctx, stopped context.Context, clock Clock, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-stopped.Done():
+		return ErrStopped
+	}
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// KeyedOptions configures a Keyed limiter
+type KeyedOptions struct {
+	// Rate and Burst of the token bucket of every key, 1 token per second by default
+	Rate  float64
+	Burst int64
+	// IdleTimeout evicts the keys not used for this duration, 0 keeps them.
+	// A key is only evicted once its bucket is full again, so evicting it
+	// never changes its rate.
+	IdleTimeout time.Duration
+	// MaxKeys evicts the least recently used keys above this number, 0 does not limit them.
+	// Keys whose bucket is not full are kept, so more keys than MaxKeys are held
+	// while more keys than that were taken from during the last Burst/Rate seconds.
+	MaxKeys int
+	// Clock is the time source, the real clock when nil
+	Clock Clock
+}
+
+// Keyed rate limits every key, e.g. a target host, with its own token bucket.
+// Buckets are created lazily on the first take of a key and evicted while taking,
+// no goroutine is started.
+type Keyed struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	options KeyedOptions
+	buckets map[string]*list.Element
+	// keys from the most to the least recently used
+	recent *list.List
+}
+
+type keyedEntry struct {
+	key      string
+	bucket   *TokenBucket
+	lastUsed time.Time
+}
+
+// NewKeyed creates a keyed limiter, the buckets stop when ctx is done
+func NewKeyed(ctx context.Context, options KeyedOptions) *Keyed {
+	if options.Clock == nil {
+		options.Clock = realClock{}
+	}
+	if options.Rate <= 0 {
+		options.Rate = 1
+	}
+	return &Keyed{
+		ctx:     ctx,
+		options: options,
+		buckets: make(map[string]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// bucket returns the bucket of key, creating it if needed, and evicts the expired keys
+func (keyed *Keyed) bucket(key string) *TokenBucket {
+	keyed.mu.Lock()
+	defer keyed.mu.Unlock()
+
+	now := keyed.options.Clock.Now()
+	element, ok := keyed.buckets[key]
+	if ok {
+		element.Value.(*keyedEntry).lastUsed = now
+		keyed.recent.MoveToFront(element)
+	} else {
+		entry := &keyedEntry{
+			key:      key,
+			bucket:   NewTokenBucketWithClock(keyed.ctx, keyed.options.Rate, keyed.options.Burst, keyed.options.Clock),
+			lastUsed: now,
+		}
+		element = keyed.recent.PushFront(entry)
+		keyed.buckets[key] = element
+	}
+	keyed.evict(now, element)
+	return element.Value.(*keyedEntry).bucket
+}
+
+// evict removes the idle keys and the least recently used ones above MaxKeys,
+// except current and the keys whose bucket is not full.
+// The caller must hold keyed.mu.
+func (keyed *Keyed) evict(now time.Time, current *list.Element) {
+	for element := keyed.recent.Back(); element != nil; {
+		entry := element.Value.(*keyedEntry)
+		idle := keyed.options.IdleTimeout > 0 && now.Sub(entry.lastUsed) >= keyed.options.IdleTimeout
+		over := keyed.options.MaxKeys > 0 && keyed.recent.Len() > keyed.options.MaxKeys
+		if !idle && !over {
+			return
+		}
+		prev := element.Prev()
+		if element != current && entry.bucket.full() {
+			keyed.recent.Remove(element)
+			delete(keyed.buckets, entry.key)
+		}
+		element = prev
+	}
+}
+
+// Take one token from the bucket of key
+func (keyed *Keyed) Take(key string) {
+	keyed.bucket(key).Take()
+}
+
+// TakeWithContext takes one token from the bucket of key, giving up when ctx is done
+func (keyed *Keyed) TakeWithContext(ctx context.Context, key string) error {
+	return keyed.bucket(key).TakeWithContext(ctx)
+}
+
+// TryTake takes one token from the bucket of key if one is available without waiting
+func (keyed *Keyed) TryTake(key string) bool {
+	return keyed.bucket(key).TryTake()
+}
+
+// Len returns the number of keys held
+func (keyed *Keyed) Len() int {
+	keyed.mu.Lock()
+	defer keyed.mu.Unlock()
+
+	return keyed.recent.Len()
+}
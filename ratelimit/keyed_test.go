@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyed(t *testing.T) {
+	t.Run("Independent keys", func(t *testing.T) {
+		clock := newFakeClock()
+		keyed := NewKeyed(context.Background(), KeyedOptions{Rate: 1, Burst: 2, Clock: clock})
+		require.True(t, keyed.TryTake("a.com"))
+		require.True(t, keyed.TryTake("a.com"))
+		require.False(t, keyed.TryTake("a.com"))
+		require.True(t, keyed.TryTake("b.com"))
+
+		done := takeAsync(func() { keyed.Take("a.com") })
+		requireBlocked(t, clock, done)
+		clock.Advance(time.Second)
+		requireDone(t, done)
+		require.Equal(t, 2, keyed.Len())
+	})
+
+	t.Run("Idle eviction", func(t *testing.T) {
+		clock := newFakeClock()
+		keyed := NewKeyed(context.Background(), KeyedOptions{Rate: 1, Burst: 1, IdleTimeout: time.Minute, Clock: clock})
+		keyed.Take("a.com")
+		clock.Advance(30 * time.Second)
+		keyed.Take("b.com")
+		require.Equal(t, 2, keyed.Len())
+		clock.Advance(30 * time.Second)
+		keyed.Take("c.com")
+		require.Equal(t, 2, keyed.Len(), "a.com idle for a minute")
+	})
+
+	t.Run("Max keys", func(t *testing.T) {
+		clock := newFakeClock()
+		keyed := NewKeyed(context.Background(), KeyedOptions{Rate: 1, Burst: 1, MaxKeys: 2, Clock: clock})
+		keyed.Take("a.com")
+		keyed.Take("b.com")
+		require.False(t, keyed.TryTake("a.com"))
+
+		// the buckets of a.com and b.com are not full, they are kept above MaxKeys
+		keyed.Take("c.com")
+		require.Equal(t, 3, keyed.Len())
+		require.False(t, keyed.TryTake("b.com"))
+
+		// once refilled the least recently used keys are evicted
+		clock.Advance(time.Second)
+		keyed.Take("d.com")
+		require.Equal(t, 2, keyed.Len())
+		require.False(t, keyed.TryTake("d.com"))
+	})
+
+	t.Run("Idle key with a partly used bucket", func(t *testing.T) {
+		clock := newFakeClock()
+		keyed := NewKeyed(context.Background(), KeyedOptions{Rate: 0.1, Burst: 1, IdleTimeout: 5 * time.Second, Clock: clock})
+		keyed.Take("a.com")
+		clock.Advance(6 * time.Second)
+		keyed.Take("b.com")
+		require.Equal(t, 2, keyed.Len())
+		require.False(t, keyed.TryTake("a.com"))
+
+		clock.Advance(10 * time.Second)
+		require.True(t, keyed.TryTake("a.com"))
+		clock.Advance(20 * time.Second)
+		keyed.Take("c.com")
+		require.Equal(t, 1, keyed.Len())
+	})
+
+	t.Run("Default rate", func(t *testing.T) {
+		clock := newFakeClock()
+		keyed := NewKeyed(context.Background(), KeyedOptions{Clock: clock})
+		keyed.Take("a.com")
+		done := takeAsync(func() { keyed.Take("a.com") })
+		requireBlocked(t, clock, done)
+		clock.Advance(time.Second)
+		requireDone(t, done)
+	})
+
+	t.Run("No goroutine per key", func(t *testing.T) {
+		keyed := NewKeyed(context.Background(), KeyedOptions{Rate: 1, Burst: 1})
+		before := runtime.NumGoroutine()
+		for i := 0; i < 1000; i++ {
+			keyed.Take(fmt.Sprintf("host%d.com", i))
+		}
+		require.Equal(t, 1000, keyed.Len())
+		require.LessOrEqual(t, runtime.NumGoroutine(), before)
+	})
+}
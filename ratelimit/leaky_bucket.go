@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LeakyBucket lets the tokens out one by one at a constant rate:
+// two tokens are always at least 1/rate seconds apart, there is no burst.
+type LeakyBucket struct {
+	mu       sync.Mutex
+	interval time.Duration
+	// time the next token leaks out
+	next  time.Time
+	clock Clock
+	ctx   context.Context
+}
+
+// NewLeakyBucket creates a leaky bucket letting rate tokens out per second.
+// With a zero rate only the first token leaks out.
+func NewLeakyBucket(ctx context.Context, rate float64) *LeakyBucket {
+	return NewLeakyBucketWithClock(ctx, rate, realClock{})
+}
+
+// NewLeakyBucketWithClock creates a leaky bucket using clock as time source
+func NewLeakyBucketWithClock(ctx context.Context, rate float64, clock Clock) *LeakyBucket {
+	interval := maxWait
+	if rate > 0 {
+		interval = seconds(1 / rate)
+	}
+	if interval < 1 {
+		interval = 1
+	}
+	return &LeakyBucket{
+		interval: interval,
+		next:     clock.Now(),
+		clock:    clock,
+		ctx:      ctx,
+	}
+}
+
+// reserve the next n slots and returns how long to wait for the first one.
+// Once reserved, the caller waits its turn then the bucket waits the interval
+// of every slot, which takes n-1 intervals before the last one.
+func (bucket *LeakyBucket) reserve(n int64) (time.Time, time.Duration) {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	now := bucket.clock.Now()
+	if bucket.next.Before(now) {
+		bucket.next = now
+	}
+	slot := bucket.next.Add(bucket.intervals(n - 1))
+	bucket.next = slot.Add(bucket.interval)
+	return slot, slot.Sub(now)
+}
+
+// intervals returns the duration of n intervals, up to maxWait
+func (bucket *LeakyBucket) intervals(n int64) time.Duration {
+	if n > 0 && bucket.interval > maxWait/time.Duration(n) {
+		return maxWait
+	}
+	return time.Duration(n) * bucket.interval
+}
+
+// cancel gives back the last reserved slots if no later one was reserved
+func (bucket *LeakyBucket) cancel(slot time.Time, n int64) {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	if bucket.next.Equal(slot.Add(bucket.interval)) {
+		bucket.next = bucket.next.Add(-bucket.intervals(n))
+	}
+}
+
+// wait for n slots, waiting until the last one or ctx is done
+func (bucket *LeakyBucket) wait(ctx context.Context, n int64) error {
+	if bucket.ctx.Err() != nil {
+		return ErrStopped
+	}
+	slot, wait := bucket.reserve(n)
+	err := sleep(ctx, bucket.ctx, bucket.clock, wait)
+	if err != nil {
+		bucket.cancel(slot, n)
+	}
+	return err
+}
+
+// Take one token, waiting its turn.
+// It returns without a token once the bucket is stopped.
+func (bucket *LeakyBucket) Take() {
+	_ = bucket.wait(context.Background(), 1)
+}
+
+// TakeWithContext takes one token, giving up when ctx is done.
+// It returns ctx.Err() or ErrStopped when no token was taken.
+func (bucket *LeakyBucket) TakeWithContext(ctx context.Context) error {
+	return bucket.wait(ctx, 1)
+}
+
+// TryTake takes one token if it can leak out now
+func (bucket *LeakyBucket) TryTake() bool {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	now := bucket.clock.Now()
+	if bucket.ctx.Err() != nil || bucket.next.After(now) {
+		return false
+	}
+	bucket.next = now.Add(bucket.interval)
+	return true
+}
+
+// TakeN takes n tokens, waiting the turn of the last one
+func (bucket *LeakyBucket) TakeN(n int64) {
+	_ = bucket.wait(context.Background(), n)
+}
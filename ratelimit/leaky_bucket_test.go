@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeakyBucket(t *testing.T) {
+	t.Run("Constant rate", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewLeakyBucketWithClock(context.Background(), 4, clock)
+		bucket.Take()
+		require.False(t, bucket.TryTake(), "no burst")
+
+		done := takeAsync(bucket.Take)
+		requireBlocked(t, clock, done)
+		clock.Advance(249 * time.Millisecond)
+		requireBlocked(t, clock, done)
+		clock.Advance(time.Millisecond)
+		requireDone(t, done)
+
+		clock.Advance(time.Hour)
+		require.True(t, bucket.TryTake())
+		require.False(t, bucket.TryTake(), "idle time is not saved up")
+	})
+
+	t.Run("Take N", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewLeakyBucketWithClock(context.Background(), 2, clock)
+		done := takeAsync(func() { bucket.TakeN(3) })
+		requireBlocked(t, clock, done)
+		clock.Advance(time.Second)
+		requireDone(t, done)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewLeakyBucketWithClock(context.Background(), 1, clock)
+		bucket.Take()
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error, 1)
+		go func() { errs <- bucket.TakeWithContext(ctx) }()
+		require.Eventually(t, func() bool { return clock.Waiters() > 0 }, time.Second, time.Millisecond)
+		cancel()
+		require.ErrorIs(t, <-errs, context.Canceled)
+
+		// the cancelled slot is given back
+		clock.Advance(time.Second)
+		require.True(t, bucket.TryTake())
+	})
+
+	t.Run("Zero rate", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewLeakyBucketWithClock(context.Background(), 0, clock)
+		bucket.Take()
+		require.False(t, bucket.TryTake())
+
+		done := takeAsync(func() { bucket.TakeN(5) })
+		requireBlocked(t, clock, done)
+		clock.Advance(365 * 24 * time.Hour)
+		requireBlocked(t, clock, done)
+		require.False(t, bucket.TryTake())
+	})
+}
@@ -0,0 +1,30 @@
+package ratelimit
+
+import "context"
+
+// Limiter takes tokens according to a rate limiting algorithm:
+//   - FixedWindow allows max tokens at the start of every window (New)
+//   - SlidingWindowLog allows max tokens in any window ending now (NewSlidingWindowLog)
+//   - SlidingWindowCounter approximates the sliding log with two counters (NewSlidingWindowCounter)
+//   - TokenBucket refills tokens continuously up to a burst (NewTokenBucket)
+//   - LeakyBucket lets the tokens out at a constant rate, without burst (NewLeakyBucket)
+type Limiter interface {
+	// Take one token, waiting until it is available.
+	// It returns without a token once the limiter is stopped.
+	Take()
+	// TakeWithContext takes one token, giving up when ctx is done.
+	// It returns ctx.Err() or ErrStopped when no token was taken.
+	TakeWithContext(ctx context.Context) error
+	// TryTake takes one token if one is available without waiting
+	TryTake() bool
+	// TakeN takes n tokens
+	TakeN(n int64)
+}
+
+var (
+	_ Limiter = (*FixedWindow)(nil)
+	_ Limiter = (*SlidingWindowLog)(nil)
+	_ Limiter = (*SlidingWindowCounter)(nil)
+	_ Limiter = (*TokenBucket)(nil)
+	_ Limiter = (*LeakyBucket)(nil)
+)
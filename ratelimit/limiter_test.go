@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	limiters := map[string]func(ctx context.Context) Limiter{
+		"FixedWindow":          func(ctx context.Context) Limiter { return New(ctx, 100, time.Second) },
+		"SlidingWindowLog":     func(ctx context.Context) Limiter { return NewSlidingWindowLog(ctx, 100, time.Second) },
+		"SlidingWindowCounter": func(ctx context.Context) Limiter { return NewSlidingWindowCounter(ctx, 100, time.Second) },
+		"TokenBucket":          func(ctx context.Context) Limiter { return NewTokenBucket(ctx, 100, 100) },
+		"LeakyBucket":          func(ctx context.Context) Limiter { return NewLeakyBucket(ctx, 1000) },
+	}
+	for name, newLimiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			limiter := newLimiter(ctx)
+			limiter.TakeN(10)
+			require.NoError(t, limiter.TakeWithContext(context.Background()))
+			cancel()
+			done := takeAsync(func() { limiter.TakeN(1000) })
+			requireDone(t, done)
+		})
+	}
+}
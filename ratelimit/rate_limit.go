@@ -2,66 +2,133 @@ package ratelimit
 
 import (
 	"context"
+	"errors"
 	"math"
+	"sync/atomic"
 	"time"
 )
 
-// Limiter allows a burst of request during the defined duration
-type Limiter struct {
-	maxCount int64
+// ErrStopped is returned when taking a token from a limiter whose context is done
+var ErrStopped = errors.New("ratelimit: limiter stopped")
+
+// FixedWindow allows a burst of request during the defined duration
+type FixedWindow struct {
+	maxCount atomic.Int64
 	count    int64
 	ticker   *time.Ticker
 	tokens   chan struct{}
-	ctx      context.Context
+	// closed once run returned, no token will be sent anymore
+	done chan struct{}
+	ctx  context.Context
 }
 
-func (limiter *Limiter) run() {
+func (limiter *FixedWindow) run() {
+	defer close(limiter.done)
+	defer limiter.ticker.Stop()
+
 	for {
 		if limiter.count <= 0 {
-			<-limiter.ticker.C
-			limiter.count = limiter.maxCount
+			select {
+			case <-limiter.ctx.Done():
+				return
+			case <-limiter.ticker.C:
+				limiter.count = limiter.maxCount.Load()
+			}
 		}
 
 		select {
 		case <-limiter.ctx.Done():
-			limiter.ticker.Stop()
 			return
 		case limiter.tokens <- struct{}{}:
 			limiter.count--
 		case <-limiter.ticker.C:
-			limiter.count = limiter.maxCount
+			limiter.count = limiter.maxCount.Load()
 		}
 	}
 }
 
-// Take one token from the bucket
-func (rateLimiter *Limiter) Take() {
-	<-rateLimiter.tokens
+// Take one token from the bucket.
+// It returns without a token once the limiter is stopped.
+func (rateLimiter *FixedWindow) Take() {
+	select {
+	case <-rateLimiter.tokens:
+	case <-rateLimiter.done:
+	}
+}
+
+// TakeWithContext takes one token from the bucket, giving up when ctx is done.
+// It returns ctx.Err() or ErrStopped when no token was taken.
+func (rateLimiter *FixedWindow) TakeWithContext(ctx context.Context) error {
+	select {
+	case <-rateLimiter.tokens:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-rateLimiter.done:
+		return ErrStopped
+	}
+}
+
+// TryTake takes one token if one is available without waiting
+func (rateLimiter *FixedWindow) TryTake() bool {
+	select {
+	case <-rateLimiter.tokens:
+		return true
+	default:
+		return false
+	}
+}
+
+// TakeN takes n tokens from the bucket
+func (rateLimiter *FixedWindow) TakeN(n int64) {
+	for i := int64(0); i < n; i++ {
+		rateLimiter.Take()
+	}
+}
+
+// SetMax changes the tokens amount, it applies from the next interval.
+// A negative amount is ignored.
+func (rateLimiter *FixedWindow) SetMax(max int64) {
+	if max < 0 {
+		return
+	}
+	rateLimiter.maxCount.Store(max)
+}
+
+// SetDuration changes the interval, the current one restarts from now.
+// A non-positive interval is ignored.
+func (rateLimiter *FixedWindow) SetDuration(duration time.Duration) {
+	if duration <= 0 {
+		return
+	}
+	rateLimiter.ticker.Reset(duration)
 }
 
 // New creates a new limiter instance with the tokens amount and the interval
-func New(ctx context.Context, max int64, duration time.Duration) *Limiter {
-	limiter := &Limiter{
-		maxCount: max,
-		count:    max,
-		ticker:   time.NewTicker(duration),
-		tokens:   make(chan struct{}),
-		ctx:      ctx,
+func New(ctx context.Context, max int64, duration time.Duration) *FixedWindow {
+	limiter := &FixedWindow{
+		count:  max,
+		ticker: time.NewTicker(duration),
+		tokens: make(chan struct{}),
+		done:   make(chan struct{}),
+		ctx:    ctx,
 	}
+	limiter.maxCount.Store(max)
 	go limiter.run()
 
 	return limiter
 }
 
 // NewUnlimited create a bucket with approximated unlimited tokens
-func NewUnlimited(ctx context.Context) *Limiter {
-	limiter := &Limiter{
-		maxCount: math.MaxInt64,
-		count:    math.MaxInt64,
-		ticker:   time.NewTicker(time.Millisecond),
-		tokens:   make(chan struct{}),
-		ctx:      ctx,
+func NewUnlimited(ctx context.Context) *FixedWindow {
+	limiter := &FixedWindow{
+		count:  math.MaxInt64,
+		ticker: time.NewTicker(time.Millisecond),
+		tokens: make(chan struct{}),
+		done:   make(chan struct{}),
+		ctx:    ctx,
 	}
+	limiter.maxCount.Store(math.MaxInt64)
 	go limiter.run()
 
 	return limiter
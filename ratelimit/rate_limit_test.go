@@ -40,4 +40,58 @@ func TestRateLimit(t *testing.T) {
 		require.Equal(t, count, 1000)
 		require.True(t, took < time.Duration(1*time.Second))
 	})
+
+	t.Run("Take Returns Once Stopped", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		limiter := New(ctx, 1, time.Hour)
+		limiter.Take()
+		done := make(chan struct{})
+		go func() {
+			limiter.Take()
+			close(done)
+		}()
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("take blocked after the limiter was stopped")
+		}
+		require.ErrorIs(t, limiter.TakeWithContext(context.Background()), ErrStopped)
+	})
+
+	t.Run("Take With Context", func(t *testing.T) {
+		limiter := New(context.Background(), 1, time.Hour)
+		require.NoError(t, limiter.TakeWithContext(context.Background()))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, limiter.TakeWithContext(ctx), context.DeadlineExceeded)
+	})
+
+	t.Run("Try Take", func(t *testing.T) {
+		limiter := New(context.Background(), 2, time.Hour)
+		require.Eventually(t, limiter.TryTake, time.Second, time.Millisecond)
+		require.Eventually(t, limiter.TryTake, time.Second, time.Millisecond)
+		require.False(t, limiter.TryTake())
+	})
+
+	t.Run("Take N", func(t *testing.T) {
+		limiter := New(context.Background(), 3, 100*time.Millisecond)
+		start := time.Now()
+		limiter.TakeN(4)
+		require.True(t, time.Since(start) >= 100*time.Millisecond)
+	})
+
+	t.Run("Set Max And Duration", func(t *testing.T) {
+		limiter := New(context.Background(), 1, time.Hour)
+		limiter.Take()
+		limiter.SetMax(5)
+		limiter.SetMax(-1)
+		limiter.SetDuration(50 * time.Millisecond)
+		limiter.SetDuration(0)
+		start := time.Now()
+		limiter.TakeN(5)
+		took := time.Since(start)
+		require.True(t, took >= 50*time.Millisecond)
+		require.True(t, took < time.Second)
+	})
 }
@@ -0,0 +1,217 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SlidingWindowLog allows max tokens in any window of the duration ending now.
+// It keeps the time of the tokens taken during the last window.
+type SlidingWindowLog struct {
+	mu       sync.Mutex
+	max      int64
+	duration time.Duration
+	// take times, oldest first
+	log   []time.Time
+	clock Clock
+	ctx   context.Context
+}
+
+// NewSlidingWindowLog creates a sliding window log allowing max tokens per duration
+func NewSlidingWindowLog(ctx context.Context, max int64, duration time.Duration) *SlidingWindowLog {
+	return NewSlidingWindowLogWithClock(ctx, max, duration, realClock{})
+}
+
+// NewSlidingWindowLogWithClock creates a sliding window log using clock as time source
+func NewSlidingWindowLogWithClock(ctx context.Context, max int64, duration time.Duration, clock Clock) *SlidingWindowLog {
+	if max < 1 {
+		max = 1
+	}
+	if duration < 1 {
+		duration = 1
+	}
+	return &SlidingWindowLog{
+		max:      max,
+		duration: duration,
+		log:      make([]time.Time, 0, max),
+		clock:    clock,
+		ctx:      ctx,
+	}
+}
+
+// take records a token if the window allows it, else returns how long to wait before retrying
+func (window *SlidingWindowLog) take() (bool, time.Duration) {
+	window.mu.Lock()
+	defer window.mu.Unlock()
+
+	now := window.clock.Now()
+	start := now.Add(-window.duration)
+	expired := 0
+	for expired < len(window.log) && !window.log[expired].After(start) {
+		expired++
+	}
+	window.log = append(window.log[:0], window.log[expired:]...)
+
+	if int64(len(window.log)) < window.max {
+		window.log = append(window.log, now)
+		return true, 0
+	}
+	return false, window.log[0].Sub(start)
+}
+
+// TakeWithContext takes one token, giving up when ctx is done.
+// It returns ctx.Err() or ErrStopped when no token was taken.
+func (window *SlidingWindowLog) TakeWithContext(ctx context.Context) error {
+	for {
+		if window.ctx.Err() != nil {
+			return ErrStopped
+		}
+		ok, wait := window.take()
+		if ok {
+			return nil
+		}
+		if err := sleep(ctx, window.ctx, window.clock, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Take one token, waiting until it is available.
+// It returns without a token once the limiter is stopped.
+func (window *SlidingWindowLog) Take() {
+	_ = window.TakeWithContext(context.Background())
+}
+
+// TryTake takes one token if one is available without waiting
+func (window *SlidingWindowLog) TryTake() bool {
+	if window.ctx.Err() != nil {
+		return false
+	}
+	ok, _ := window.take()
+	return ok
+}
+
+// TakeN takes n tokens
+func (window *SlidingWindowLog) TakeN(n int64) {
+	for i := int64(0); i < n; i++ {
+		window.Take()
+	}
+}
+
+// SlidingWindowCounter approximates a sliding window log with the counts of the current
+// and the previous fixed windows, the previous one weighted by its part still in the
+// sliding window. It uses constant memory whatever the max.
+type SlidingWindowCounter struct {
+	mu       sync.Mutex
+	max      int64
+	duration time.Duration
+	// start of the current fixed window
+	start    time.Time
+	current  int64
+	previous int64
+	clock    Clock
+	ctx      context.Context
+}
+
+// NewSlidingWindowCounter creates a sliding window counter allowing max tokens per duration
+func NewSlidingWindowCounter(ctx context.Context, max int64, duration time.Duration) *SlidingWindowCounter {
+	return NewSlidingWindowCounterWithClock(ctx, max, duration, realClock{})
+}
+
+// NewSlidingWindowCounterWithClock creates a sliding window counter using clock as time source
+func NewSlidingWindowCounterWithClock(ctx context.Context, max int64, duration time.Duration, clock Clock) *SlidingWindowCounter {
+	if max < 1 {
+		max = 1
+	}
+	if duration < 1 {
+		duration = 1
+	}
+	return &SlidingWindowCounter{
+		max:      max,
+		duration: duration,
+		start:    clock.Now(),
+		clock:    clock,
+		ctx:      ctx,
+	}
+}
+
+// take counts a token if the estimated count allows it, else returns how long to wait before retrying
+func (window *SlidingWindowCounter) take() (bool, time.Duration) {
+	window.mu.Lock()
+	defer window.mu.Unlock()
+
+	now := window.clock.Now()
+	if elapsed := now.Sub(window.start); elapsed >= window.duration {
+		windows := elapsed / window.duration
+		window.start = window.start.Add(windows * window.duration)
+		if windows == 1 {
+			window.previous = window.current
+		} else {
+			window.previous = 0
+		}
+		window.current = 0
+	}
+
+	elapsed := now.Sub(window.start)
+	weight := 1 - float64(elapsed)/float64(window.duration)
+	if float64(window.previous)*weight+float64(window.current+1) <= float64(window.max) {
+		window.current++
+		return true, 0
+	}
+
+	end := window.duration - elapsed
+	free := window.max - window.current - 1
+	if window.previous == 0 || free < 0 {
+		return false, end
+	}
+	// the previous window weight allowing one more token
+	needed := 1 - float64(free)/float64(window.previous)
+	wait := time.Duration(needed*float64(window.duration)) - elapsed
+	if wait <= 0 {
+		wait = 1
+	}
+	if wait > end {
+		wait = end
+	}
+	return false, wait
+}
+
+// TakeWithContext takes one token, giving up when ctx is done.
+// It returns ctx.Err() or ErrStopped when no token was taken.
+func (window *SlidingWindowCounter) TakeWithContext(ctx context.Context) error {
+	for {
+		if window.ctx.Err() != nil {
+			return ErrStopped
+		}
+		ok, wait := window.take()
+		if ok {
+			return nil
+		}
+		if err := sleep(ctx, window.ctx, window.clock, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Take one token, waiting until it is available.
+// It returns without a token once the limiter is stopped.
+func (window *SlidingWindowCounter) Take() {
+	_ = window.TakeWithContext(context.Background())
+}
+
+// TryTake takes one token if one is available without waiting
+func (window *SlidingWindowCounter) TryTake() bool {
+	if window.ctx.Err() != nil {
+		return false
+	}
+	ok, _ := window.take()
+	return ok
+}
+
+// TakeN takes n tokens
+func (window *SlidingWindowCounter) TakeN(n int64) {
+	for i := int64(0); i < n; i++ {
+		window.Take()
+	}
+}
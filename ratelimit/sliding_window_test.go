@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlidingWindowLog(t *testing.T) {
+	t.Run("Max per sliding window", func(t *testing.T) {
+		clock := newFakeClock()
+		window := NewSlidingWindowLogWithClock(context.Background(), 2, time.Second, clock)
+		window.Take()
+		clock.Advance(600 * time.Millisecond)
+		window.Take()
+		require.False(t, window.TryTake())
+
+		// the first token leaves the window 1s after it was taken
+		done := takeAsync(window.Take)
+		requireBlocked(t, clock, done)
+		clock.Advance(399 * time.Millisecond)
+		requireBlocked(t, clock, done)
+		clock.Advance(time.Millisecond)
+		requireDone(t, done)
+		require.False(t, window.TryTake())
+		clock.Advance(600 * time.Millisecond)
+		require.True(t, window.TryTake())
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		clock := newFakeClock()
+		ctx, cancel := context.WithCancel(context.Background())
+		window := NewSlidingWindowLogWithClock(ctx, 1, time.Second, clock)
+		window.Take()
+		errs := make(chan error, 1)
+		go func() { errs <- window.TakeWithContext(context.Background()) }()
+		require.Eventually(t, func() bool { return clock.Waiters() > 0 }, time.Second, time.Millisecond)
+		cancel()
+		require.ErrorIs(t, <-errs, ErrStopped)
+	})
+
+	t.Run("Zero duration", func(t *testing.T) {
+		clock := newFakeClock()
+		window := NewSlidingWindowLogWithClock(context.Background(), 1, 0, clock)
+		require.True(t, window.TryTake())
+		require.False(t, window.TryTake())
+		clock.Advance(time.Nanosecond)
+		require.True(t, window.TryTake())
+	})
+}
+
+func TestSlidingWindowCounter(t *testing.T) {
+	t.Run("Weighted previous window", func(t *testing.T) {
+		clock := newFakeClock()
+		window := NewSlidingWindowCounterWithClock(context.Background(), 4, time.Second, clock)
+		window.TakeN(4)
+		require.False(t, window.TryTake())
+
+		// a quarter into the next window, 3 of the previous 4 tokens still count
+		clock.Advance(1250 * time.Millisecond)
+		require.True(t, window.TryTake())
+		require.False(t, window.TryTake())
+
+		// one more token once half of the previous window left
+		done := takeAsync(window.Take)
+		requireBlocked(t, clock, done)
+		clock.Advance(249 * time.Millisecond)
+		requireBlocked(t, clock, done)
+		clock.Advance(time.Millisecond)
+		requireDone(t, done)
+	})
+
+	t.Run("Idle windows", func(t *testing.T) {
+		clock := newFakeClock()
+		window := NewSlidingWindowCounterWithClock(context.Background(), 2, time.Second, clock)
+		window.TakeN(2)
+		clock.Advance(2 * time.Second)
+		require.True(t, window.TryTake())
+		require.True(t, window.TryTake())
+		require.False(t, window.TryTake())
+	})
+
+	t.Run("Zero duration", func(t *testing.T) {
+		clock := newFakeClock()
+		window := NewSlidingWindowCounterWithClock(context.Background(), 1, 0, clock)
+		require.True(t, window.TryTake())
+		require.False(t, window.TryTake())
+		clock.Advance(2 * time.Nanosecond)
+		require.True(t, window.TryTake())
+	})
+}
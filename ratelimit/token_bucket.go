@@ -0,0 +1,194 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket refills tokens continuously at rate per second up to burst,
+// smoothing the traffic instead of allowing a burst at the start of every window.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	// closed when the rate changes, so the waits computed with the previous rate are computed again
+	changed chan struct{}
+	clock   Clock
+	ctx     context.Context
+}
+
+// NewTokenBucket creates a token bucket refilled with rate tokens per second
+// (fractional rates like 0.5 are allowed) holding at most burst tokens.
+// The bucket starts full, with a zero rate it is not refilled until SetRate.
+func NewTokenBucket(ctx context.Context, rate float64, burst int64) *TokenBucket {
+	return NewTokenBucketWithClock(ctx, rate, burst, realClock{})
+}
+
+// NewTokenBucketWithClock creates a token bucket using clock as time source
+func NewTokenBucketWithClock(ctx context.Context, rate float64, burst int64, clock Clock) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	if rate < 0 {
+		rate = 0
+	}
+	return &TokenBucket{
+		rate:    rate,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		last:    clock.Now(),
+		changed: make(chan struct{}),
+		clock:   clock,
+		ctx:     ctx,
+	}
+}
+
+// SetRate changes the number of tokens refilled per second, the pending takes wait with the new rate
+func (bucket *TokenBucket) SetRate(rate float64) {
+	if rate < 0 {
+		rate = 0
+	}
+
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	bucket.refill(bucket.clock.Now())
+	bucket.rate = rate
+	close(bucket.changed)
+	bucket.changed = make(chan struct{})
+}
+
+// Rate returns the number of tokens refilled per second
+func (bucket *TokenBucket) Rate() float64 {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	return bucket.rate
+}
+
+// SetBurst changes the maximum number of tokens held by the bucket
+func (bucket *TokenBucket) SetBurst(burst int64) {
+	if burst < 1 {
+		burst = 1
+	}
+
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	bucket.refill(bucket.clock.Now())
+	bucket.burst = float64(burst)
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+}
+
+// full reports whether the bucket holds burst tokens again
+func (bucket *TokenBucket) full() bool {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	bucket.refill(bucket.clock.Now())
+	return bucket.tokens >= bucket.burst
+}
+
+// refill adds the tokens accumulated since the last refill.
+// The caller must hold bucket.mu.
+func (bucket *TokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens += elapsed.Seconds() * bucket.rate
+		if bucket.tokens > bucket.burst {
+			bucket.tokens = bucket.burst
+		}
+	}
+	bucket.last = now
+}
+
+// reserve takes n tokens, possibly in advance, and returns how long to wait before using them
+// and the channel closed if the rate changes meanwhile
+func (bucket *TokenBucket) reserve(n float64) (time.Duration, chan struct{}) {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	bucket.refill(bucket.clock.Now())
+	bucket.tokens -= n
+	if bucket.tokens >= 0 {
+		return 0, bucket.changed
+	}
+	if bucket.rate <= 0 {
+		return maxWait, bucket.changed
+	}
+	return seconds(-bucket.tokens / bucket.rate), bucket.changed
+}
+
+// cancel gives back n tokens reserved but not used
+func (bucket *TokenBucket) cancel(n float64) {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	bucket.refill(bucket.clock.Now())
+	bucket.tokens += n
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+}
+
+// wait takes n tokens, waiting until they are available or ctx is done
+func (bucket *TokenBucket) wait(ctx context.Context, n float64) error {
+	for {
+		if bucket.ctx.Err() != nil {
+			return ErrStopped
+		}
+		wait, changed := bucket.reserve(n)
+		if wait <= 0 {
+			return nil
+		}
+		select {
+		case <-bucket.clock.After(wait):
+			return nil
+		case <-changed:
+			bucket.cancel(n)
+		case <-ctx.Done():
+			bucket.cancel(n)
+			return ctx.Err()
+		case <-bucket.ctx.Done():
+			bucket.cancel(n)
+			return ErrStopped
+		}
+	}
+}
+
+// Take one token from the bucket, waiting until it is available.
+// It returns without a token once the bucket is stopped.
+func (bucket *TokenBucket) Take() {
+	_ = bucket.wait(context.Background(), 1)
+}
+
+// TakeWithContext takes one token from the bucket, giving up when ctx is done.
+// It returns ctx.Err() or ErrStopped when no token was taken.
+func (bucket *TokenBucket) TakeWithContext(ctx context.Context) error {
+	return bucket.wait(ctx, 1)
+}
+
+// TryTake takes one token if one is available without waiting
+func (bucket *TokenBucket) TryTake() bool {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	if bucket.ctx.Err() != nil {
+		return false
+	}
+	bucket.refill(bucket.clock.Now())
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// TakeN takes n tokens from the bucket at once, n may exceed the burst
+func (bucket *TokenBucket) TakeN(n int64) {
+	_ = bucket.wait(context.Background(), float64(n))
+}
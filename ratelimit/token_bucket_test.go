@@ -0,0 +1,250 @@
+package ratelimit
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock only moves forward when advanced
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (clock *fakeClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+func (clock *fakeClock) After(d time.Duration) <-chan time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- clock.now
+		return ch
+	}
+	clock.waiters = append(clock.waiters, fakeWaiter{clock.now.Add(d), ch})
+	return ch
+}
+
+// Advance moves the clock forward and wakes the expired waiters
+func (clock *fakeClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+	sort.Slice(clock.waiters, func(i, j int) bool {
+		return clock.waiters[i].deadline.Before(clock.waiters[j].deadline)
+	})
+	waiters := clock.waiters[:0]
+	for _, waiter := range clock.waiters {
+		if waiter.deadline.After(clock.now) {
+			waiters = append(waiters, waiter)
+			continue
+		}
+		waiter.ch <- clock.now
+	}
+	clock.waiters = waiters
+}
+
+// Waiters returns the number of pending After calls
+func (clock *fakeClock) Waiters() int {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return len(clock.waiters)
+}
+
+// takeAsync calls take in a goroutine and returns a channel closed once it returned
+func takeAsync(take func()) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		take()
+		close(done)
+	}()
+	return done
+}
+
+func requireBlocked(t *testing.T, clock *fakeClock, done chan struct{}) {
+	require.Eventually(t, func() bool { return clock.Waiters() > 0 }, time.Second, time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("take returned before the token was available")
+	default:
+	}
+}
+
+func requireDone(t *testing.T, done chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("take did not return")
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	t.Run("Burst then refill rate", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewTokenBucketWithClock(context.Background(), 10, 5, clock)
+		for i := 0; i < 5; i++ {
+			bucket.Take()
+		}
+		require.Equal(t, 0, clock.Waiters())
+
+		done := takeAsync(bucket.Take)
+		requireBlocked(t, clock, done)
+		clock.Advance(99 * time.Millisecond)
+		requireBlocked(t, clock, done)
+		clock.Advance(time.Millisecond)
+		requireDone(t, done)
+
+		// the bucket never holds more than burst tokens
+		clock.Advance(time.Hour)
+		for i := 0; i < 5; i++ {
+			bucket.Take()
+		}
+		done = takeAsync(bucket.Take)
+		requireBlocked(t, clock, done)
+		clock.Advance(100 * time.Millisecond)
+		requireDone(t, done)
+	})
+
+	t.Run("Fractional rate", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewTokenBucketWithClock(context.Background(), 0.5, 1, clock)
+		bucket.Take()
+
+		done := takeAsync(bucket.Take)
+		requireBlocked(t, clock, done)
+		clock.Advance(1999 * time.Millisecond)
+		requireBlocked(t, clock, done)
+		clock.Advance(time.Millisecond)
+		requireDone(t, done)
+	})
+
+	t.Run("Real clock", func(t *testing.T) {
+		bucket := NewTokenBucket(context.Background(), 100, 1)
+		start := time.Now()
+		for i := 0; i < 11; i++ {
+			bucket.Take()
+		}
+		require.True(t, time.Since(start) >= 100*time.Millisecond)
+	})
+
+	t.Run("Take with context", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewTokenBucketWithClock(context.Background(), 1, 1, clock)
+		require.NoError(t, bucket.TakeWithContext(context.Background()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error, 1)
+		go func() { errs <- bucket.TakeWithContext(ctx) }()
+		require.Eventually(t, func() bool { return clock.Waiters() > 0 }, time.Second, time.Millisecond)
+		cancel()
+		require.ErrorIs(t, <-errs, context.Canceled)
+
+		// the cancelled reservation is given back
+		clock.Advance(time.Second)
+		require.True(t, bucket.TryTake())
+	})
+
+	t.Run("Try take", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewTokenBucketWithClock(context.Background(), 2, 2, clock)
+		require.True(t, bucket.TryTake())
+		require.True(t, bucket.TryTake())
+		require.False(t, bucket.TryTake())
+		clock.Advance(500 * time.Millisecond)
+		require.True(t, bucket.TryTake())
+		require.False(t, bucket.TryTake())
+	})
+
+	t.Run("Take N", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewTokenBucketWithClock(context.Background(), 1, 2, clock)
+		done := takeAsync(func() { bucket.TakeN(4) })
+		requireBlocked(t, clock, done)
+		clock.Advance(time.Second)
+		requireBlocked(t, clock, done)
+		clock.Advance(time.Second)
+		requireDone(t, done)
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		clock := newFakeClock()
+		ctx, cancel := context.WithCancel(context.Background())
+		bucket := NewTokenBucketWithClock(ctx, 1, 1, clock)
+		bucket.Take()
+		done := takeAsync(bucket.Take)
+		requireBlocked(t, clock, done)
+		cancel()
+		requireDone(t, done)
+		require.False(t, bucket.TryTake())
+		require.ErrorIs(t, bucket.TakeWithContext(context.Background()), ErrStopped)
+	})
+
+	t.Run("Set rate and burst", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewTokenBucketWithClock(context.Background(), 1, 4, clock)
+		bucket.SetBurst(2)
+		require.True(t, bucket.TryTake())
+		require.True(t, bucket.TryTake())
+		require.False(t, bucket.TryTake())
+
+		bucket.SetRate(10)
+		require.Equal(t, 10.0, bucket.Rate())
+		clock.Advance(100 * time.Millisecond)
+		require.True(t, bucket.TryTake())
+		require.False(t, bucket.TryTake())
+	})
+
+	t.Run("Zero rate", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewTokenBucketWithClock(context.Background(), 0, 1, clock)
+		bucket.Take()
+
+		done := takeAsync(bucket.Take)
+		requireBlocked(t, clock, done)
+		clock.Advance(24 * time.Hour)
+		requireBlocked(t, clock, done)
+		require.False(t, bucket.TryTake())
+
+		// the pending take waits with the raised rate
+		bucket.SetRate(1)
+		require.Eventually(t, func() bool { return clock.Waiters() > 1 }, time.Second, time.Millisecond)
+		clock.Advance(time.Second)
+		requireDone(t, done)
+	})
+
+	t.Run("Tiny rate", func(t *testing.T) {
+		clock := newFakeClock()
+		bucket := NewTokenBucketWithClock(context.Background(), 1e-12, 1, clock)
+		bucket.Take()
+
+		done := takeAsync(bucket.Take)
+		requireBlocked(t, clock, done)
+		clock.Advance(365 * 24 * time.Hour)
+		requireBlocked(t, clock, done)
+		require.False(t, bucket.TryTake())
+	})
+
+	t.Run("Negative rate", func(t *testing.T) {
+		bucket := NewTokenBucketWithClock(context.Background(), -1, 1, newFakeClock())
+		require.Equal(t, 0.0, bucket.Rate())
+	})
+}